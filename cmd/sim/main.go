@@ -3,18 +3,24 @@ package main
 import (
 	"flag"
 	"log"
+	"path/filepath"
 
 	"github.com/emrzvv/lb-research/internal/balancer"
 	"github.com/emrzvv/lb-research/internal/common"
 	"github.com/emrzvv/lb-research/internal/config"
 	"github.com/emrzvv/lb-research/internal/export"
 	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/oracle"
 	"github.com/emrzvv/lb-research/internal/simulator"
 )
 
 func main() {
 	cfgPath := flag.String("cfg", "./config/default.yaml", "path to config")
 	outDir := flag.String("out", "./csv", "output directory for csv")
+	mode := flag.String("mode", "run",
+		"run: simulate and export csv; train: train the ql table and save it; "+
+			"eval: run with strategy ql and the saved table (same as run with balancer.strategy: ql); "+
+			"oracle: offline greedy reference with full knowledge of the run in -out (not an optimum)")
 	flag.Parse()
 
 	cfg, err := config.Load(*cfgPath)
@@ -23,6 +29,32 @@ func main() {
 	}
 
 	// fmt.Printf("%v", cfg)
+	switch *mode {
+	case "run":
+	case "eval":
+		cfg.Balancer.Strategy = "ql"
+	case "train":
+		table := simulator.TrainQLearning(cfg)
+		if err := table.Save(cfg.Balancer.QLTable); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("q-table saved to %s", cfg.Balancer.QLTable)
+		return
+	case "oracle":
+		res, err := oracle.Run(cfg, *outDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := oracle.WriteCSV(res, filepath.Join(*outDir, "oracle.csv")); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("oracle: served %d/%d segments (max util %.3f), online served %d (%.1f%% of greedy)",
+			res.Served, res.Segments, res.MaxUtil, res.OnlineServed, res.ServedPct())
+		return
+	default:
+		log.Fatalf("unknown mode: %s", *mode)
+	}
+
 	rng := common.NewRNG(cfg.Simulation.Seed)
 	servers := model.InitServers(cfg, rng)
 
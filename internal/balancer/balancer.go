@@ -6,25 +6,53 @@ import (
 	"github.com/emrzvv/lb-research/internal/common"
 	"github.com/emrzvv/lb-research/internal/config"
 	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
 )
 
 type Balancer interface {
-	PickServer(sessionID int64) *model.Server
+	PickServer(session *model.Session) *model.Server
 	GetServers() []*model.Server
 }
 
+// RateSource — текущая и базовая интенсивность поступления сессий
+type RateSource interface {
+	Get() float64
+	Base() float64
+}
+
+// Env — окружение симуляции, доступное балансировщику во время прогона
+type Env struct {
+	Sim   *simgo.Simulation
+	Stats *stats.Statistics
+	Rate  RateSource
+}
+
+// Attachable реализуют балансировщики, которым нужна обратная связь
+// (исходы запросов, отказы) или собственные процессы в симуляции.
+type Attachable interface {
+	Attach(env *Env)
+}
+
+// Attach подключает балансировщик (и все звенья цепочки) к окружению
+func Attach(b Balancer, env *Env) {
+	if a, ok := b.(Attachable); ok {
+		a.Attach(env)
+	}
+}
+
 type chain struct {
 	head Balancer
 	next Balancer
 }
 
-func (c *chain) PickServer(sessionID int64) *model.Server {
-	s := c.head.PickServer(sessionID)
+func (c *chain) PickServer(session *model.Session) *model.Server {
+	s := c.head.PickServer(session)
 	if s != nil {
 		return s
 	}
 	if c.next != nil {
-		return c.next.PickServer(sessionID)
+		return c.next.PickServer(session)
 	}
 	return s
 }
@@ -33,9 +61,36 @@ func (c *chain) GetServers() []*model.Server {
 	return c.head.GetServers()
 }
 
+func (c *chain) Attach(env *Env) {
+	Attach(c.head, env)
+	if c.next != nil {
+		Attach(c.next, env)
+	}
+}
+
 type factory func([]*model.Server, *config.Config, *common.RNG) Balancer
 
 func BuildChain(cfg *config.Config, servers []*model.Server, rng *common.RNG) Balancer {
+	strategies := cfg.Strategies()
+	if len(strategies) == 1 {
+		return build(cfg.Balancer.Strategy, cfg, servers, rng)
+	}
+	sw := &SwitchBalancer{current: cfg.Balancer.Strategy, strategies: make(map[string]Balancer, len(strategies))}
+	for _, strategy := range strategies {
+		sw.names = append(sw.names, strategy)
+		sw.strategies[strategy] = build(strategy, cfg, servers, rng)
+	}
+	return sw
+}
+
+func build(strategy string, cfg *config.Config, servers []*model.Server, rng *common.RNG) Balancer {
+	if len(cfg.PoPs) > 0 {
+		return NewHierarchicalBalancer(strategy, cfg, servers, rng)
+	}
+	return buildChain(strategy, cfg, servers, rng)
+}
+
+func buildChain(strategy string, cfg *config.Config, servers []*model.Server, rng *common.RNG) Balancer {
 	var registry = map[string]factory{
 		"ch": func(s []*model.Server, c *config.Config, r *common.RNG) Balancer {
 			return NewCHBalancer(servers, cfg.Balancer.CHReplicas)
@@ -49,9 +104,31 @@ func BuildChain(cfg *config.Config, servers []*model.Server, rng *common.RNG) Ba
 		"peak_ewma": func(s []*model.Server, c *config.Config, r *common.RNG) Balancer {
 			return NewPeakEWMABalancer(servers, 0.1)
 		},
+		"ts": func(s []*model.Server, c *config.Config, r *common.RNG) Balancer {
+			return NewThompsonBalancer(servers, cfg, rng)
+		},
+		"ql": func(s []*model.Server, c *config.Config, r *common.RNG) Balancer {
+			table, err := LoadQTable(cfg.Balancer.QLTable)
+			if err != nil {
+				panic("ql: " + err.Error())
+			}
+			return NewQLearningBalancer(servers, cfg, rng, table, false)
+		},
+		"nearest": func(s []*model.Server, c *config.Config, r *common.RNG) Balancer {
+			return NewNearestBalancer(servers, cfg)
+		},
+		"mpc": func(s []*model.Server, c *config.Config, r *common.RNG) Balancer {
+			return NewMPCBalancer(servers, cfg)
+		},
+		"predictive": func(s []*model.Server, c *config.Config, r *common.RNG) Balancer {
+			return NewPredictiveBalancer(
+				buildChain(cfg.Balancer.PredAffinity, cfg, servers, rng),
+				buildChain(cfg.Balancer.PredSpread, cfg, servers, rng),
+				cfg)
+		},
 	}
 
-	strategies := strings.Split(cfg.Balancer.Strategy, "+")
+	strategies := strings.Split(strategy, "+")
 	if len(strategies) == 0 {
 		panic("empty strategy")
 	}
@@ -72,3 +149,20 @@ func BuildChain(cfg *config.Config, servers []*model.Server, rng *common.RNG) Ba
 	}
 	return tail
 }
+
+// available — серверы, принимающие новые сессии; исходный срез, если принимают все
+func available(servers []*model.Server) []*model.Server {
+	for i, s := range servers {
+		if s.IsAvailable() {
+			continue
+		}
+		live := append([]*model.Server(nil), servers[:i]...)
+		for _, s := range servers[i+1:] {
+			if s.IsAvailable() {
+				live = append(live, s)
+			}
+		}
+		return live
+	}
+	return servers
+}
@@ -49,12 +49,17 @@ func newRing(servers []*model.Server, replicas int) *ring {
 	}
 }
 
+// get — первый по кольцу после hash сервер, принимающий новые сессии
+// (nil, если таких нет)
 func (r *ring) get(hash uint32) *model.Server {
 	idx := sort.Search(len(r.vnodes), func(i int) bool { return r.vnodes[i].hash >= hash })
-	if idx == len(r.vnodes) {
-		idx = 0
+	for i := range r.vnodes {
+		s := r.vnodes[(idx+i)%len(r.vnodes)].server
+		if s.IsAvailable() {
+			return s
+		}
 	}
-	return r.vnodes[idx].server
+	return nil
 }
 
 type CHBalancer struct {
@@ -74,14 +79,14 @@ func NewCHBalancer(servers []*model.Server, replicas int) *CHBalancer {
 	}
 }
 
-func (chb *CHBalancer) PickServer(sessionID int64) *model.Server {
-	sh := fnv32int64(sessionID)
+func (chb *CHBalancer) PickServer(session *model.Session) *model.Server {
+	sh := fnv32int64(session.Key())
 	chb.mu.Lock()
 	s := chb.ring.get(sh)
 	chb.mu.Unlock()
-	// fmt.Printf("server %d session %d\n", s.ID, sessionID)
-	if s.IsOverLoaded() {
-		// fmt.Printf("server %d overloaded for session %d\n", s.ID, sessionID)
+	// fmt.Printf("server %d session %d\n", s.ID, session.ID)
+	if s == nil || s.IsOverLoaded() {
+		// fmt.Printf("server %d overloaded for session %d\n", s.ID, session.ID)
 		return nil
 	}
 	return s
@@ -0,0 +1,197 @@
+package balancer
+
+import (
+	"math"
+	"sort"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+type pop struct {
+	name    string
+	region  string
+	latency float64
+	weight  float64
+	servers []*model.Server
+	local   Balancer
+}
+
+// HierarchicalBalancer — двухуровневая балансировка: глобальный селектор
+// упорядочивает PoP (по задержке, свободной ёмкости или географии),
+// а локальная стратегия выбирает сервер внутри PoP. Если локальная стратегия
+// не нашла сервер или выбранный сервер перегружен, сессия переливается в следующий PoP.
+type HierarchicalBalancer struct {
+	pops    []*pop
+	servers []*model.Server
+	cfg     *config.Config
+	global  string
+	sim     *simgo.Simulation
+	st      *stats.Statistics
+}
+
+func NewHierarchicalBalancer(strategy string, cfg *config.Config, servers []*model.Server, rng *common.RNG) *HierarchicalBalancer {
+	b := &HierarchicalBalancer{
+		servers: servers,
+		cfg:     cfg,
+		global:  cfg.Balancer.Global,
+	}
+	byName := make(map[string]*pop, len(cfg.PoPs))
+	for _, p := range cfg.PoPs {
+		pp := &pop{name: p.Name, region: p.Region, latency: p.Latency, weight: p.Weight}
+		byName[p.Name] = pp
+		b.pops = append(b.pops, pp)
+	}
+	for _, s := range servers {
+		if pp, ok := byName[s.PoP]; ok {
+			pp.servers = append(pp.servers, s)
+		}
+	}
+	for _, pp := range b.pops {
+		pp.local = buildChain(strategy, cfg, pp.servers, rng)
+	}
+	return b
+}
+
+func (b *HierarchicalBalancer) Attach(env *Env) {
+	b.sim = env.Sim
+	b.st = env.Stats
+	for _, pp := range b.pops {
+		Attach(pp.local, env)
+	}
+}
+
+// latency — задержка от клиента до PoP: по матрице регионов, если известны оба региона,
+// иначе — latency_ms из конфигурации PoP
+func (b *HierarchicalBalancer) latency(session *model.Session, pp *pop) float64 {
+	if session.Region != "" && pp.region != "" {
+		return b.cfg.RegionLatency(session.Region, pp.region)
+	}
+	return pp.latency
+}
+
+// score — чем меньше, тем предпочтительнее PoP; учитываются только серверы,
+// принимающие новые сессии (не упавшие, не выводимые и не в резерве)
+func (b *HierarchicalBalancer) score(session *model.Session, pp *pop) float64 {
+	switch b.global {
+	case "capacity":
+		free, total := 0, 0
+		for _, s := range pp.servers {
+			if !s.IsAvailable() {
+				continue
+			}
+			// занятость — как в Load: соединения (слоты сессий) вместе с очередью
+			load := s.Load()
+			s.Lock()
+			free += max(s.Parameters.MaxConnections-load, 0)
+			total += max(s.Parameters.MaxConnections, 0)
+			s.Unlock()
+		}
+		if total == 0 {
+			return math.MaxFloat64
+		}
+		return -float64(free) / float64(total)
+	default: // latency
+		owd, n := 0.0, 0
+		for _, s := range pp.servers {
+			if !s.IsAvailable() {
+				continue
+			}
+			s.Lock()
+			owd += s.CurrentOWD
+			s.Unlock()
+			n++
+		}
+		if n == 0 {
+			return math.MaxFloat64
+		}
+		return b.latency(session, pp) + owd/float64(n)
+	}
+}
+
+// home — «домашний» PoP сессии по хешу, пропорционально весам PoP (если регион клиента неизвестен)
+func (b *HierarchicalBalancer) home(session *model.Session) int {
+	total := 0.0
+	for _, pp := range b.pops {
+		total += pp.weight
+	}
+	x := float64(fnv32int64(session.Key())) / float64(math.MaxUint32) * total
+	acc := 0.0
+	for i, pp := range b.pops {
+		acc += pp.weight
+		if x <= acc {
+			return i
+		}
+	}
+	return len(b.pops) - 1
+}
+
+func (b *HierarchicalBalancer) order(session *model.Session) []*pop {
+	ordered := make([]*pop, len(b.pops))
+	copy(ordered, b.pops)
+	if b.global == "geo" {
+		if session.Region != "" {
+			sort.SliceStable(ordered, func(i, j int) bool {
+				return b.latency(session, ordered[i]) < b.latency(session, ordered[j])
+			})
+			return ordered
+		}
+		h := b.home(session)
+		ordered[0], ordered[h] = ordered[h], ordered[0]
+		sort.SliceStable(ordered[1:], func(i, j int) bool { return ordered[1+i].latency < ordered[1+j].latency })
+		return ordered
+	}
+	scores := make(map[*pop]float64, len(ordered))
+	for _, pp := range ordered {
+		scores[pp] = b.score(session, pp)
+	}
+	sort.SliceStable(ordered, func(i, j int) bool { return scores[ordered[i]] < scores[ordered[j]] })
+	return ordered
+}
+
+func (b *HierarchicalBalancer) PickServer(session *model.Session) *model.Server {
+	ordered := b.order(session)
+	var fallback *model.Server // перегруженный сервер — если свободных нет ни в одном PoP
+	var fallbackPoP *pop
+	for _, pp := range ordered {
+		if len(pp.servers) == 0 {
+			continue
+		}
+		s := pp.local.PickServer(session)
+		if s == nil {
+			continue
+		}
+		if s.IsOverLoaded() {
+			if fallback == nil {
+				fallback, fallbackPoP = s, pp
+			}
+			continue
+		}
+		b.spill(session, ordered[0], pp)
+		return s
+	}
+	if fallback != nil {
+		b.spill(session, ordered[0], fallbackPoP)
+	}
+	return fallback
+}
+
+// spill записывает перелив сессии из предпочтительного PoP
+func (b *HierarchicalBalancer) spill(session *model.Session, from, to *pop) {
+	if from == to || b.st == nil {
+		return
+	}
+	b.st.AddSpillover(&stats.SpilloverEvent{
+		SessionID: session.ID,
+		FromPoP:   from.name,
+		ToPoP:     to.name,
+		T:         b.sim.Now(),
+	})
+}
+
+func (b *HierarchicalBalancer) GetServers() []*model.Server {
+	return b.servers
+}
@@ -0,0 +1,128 @@
+package balancer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+func TestHierarchicalSpillsOverFromOverloadedPoP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cfg.yaml")
+	yaml := `
+cluster:
+  servers: 4
+  bitrate: 4
+  capacity: {type: constant, value: 40}
+  owd: {type: constant, value: 10}
+pops:
+  - {name: near, servers: 2, latency_ms: 10}
+  - {name: far, servers: 2, latency_ms: 50}
+balancer: {strategy: p2c, global: latency}
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := common.NewRNG(1)
+	servers := model.InitServers(cfg, rng)
+	b := NewHierarchicalBalancer("p2c", cfg, servers, rng)
+	st := stats.NewStatistics(cfg)
+	Attach(b, &Env{Sim: simgo.NewSimulation(), Stats: st})
+
+	if s := b.PickServer(&model.Session{ID: 1}); s.PoP != "near" {
+		t.Fatalf("picked PoP %q, want near", s.PoP)
+	}
+	// p2c в ближнем PoP всегда находит сервер, но оба перегружены
+	for _, s := range servers {
+		if s.PoP == "near" {
+			s.CurrentConnections = s.Parameters.MaxConnections
+		}
+	}
+	if s := b.PickServer(&model.Session{ID: 2}); s.PoP != "far" {
+		t.Fatalf("picked PoP %q, want far", s.PoP)
+	}
+	if len(st.Spillovers) != 1 || st.Spillovers[0].ToPoP != "far" {
+		t.Fatalf("spillovers = %v, want one into far", st.Spillovers)
+	}
+}
+
+func TestHierarchicalCapacityIgnoresUnavailableServers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cfg.yaml")
+	yaml := `
+cluster:
+  servers: 4
+  bitrate: 4
+  capacity: {type: constant, value: 40}
+pops:
+  - {name: a, servers: 2, latency_ms: 10}
+  - {name: b, servers: 2, latency_ms: 10}
+balancer: {strategy: p2c, global: capacity}
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := common.NewRNG(1)
+	servers := model.InitServers(cfg, rng)
+	b := NewHierarchicalBalancer("p2c", cfg, servers, rng)
+	st := stats.NewStatistics(cfg)
+	Attach(b, &Env{Sim: simgo.NewSimulation(), Stats: st})
+
+	// в a свободен только упавший сервер, в b — половина ёмкости обоих
+	limit := servers[0].Parameters.MaxConnections
+	servers[0].Fail(0, st, "failure")
+	servers[1].CurrentConnections = limit - 1
+	servers[2].CurrentConnections = limit / 2
+	servers[3].CurrentConnections = limit / 2
+	if s := b.PickServer(&model.Session{ID: 1}); s.PoP != "b" {
+		t.Fatalf("picked PoP %q, want b", s.PoP)
+	}
+}
+
+func TestHierarchicalCapacityCountsSlotsAndQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cfg.yaml")
+	yaml := `
+cluster:
+  servers: 4
+  bitrate: 4
+  capacity: {type: constant, value: 40}
+  sessions: {mode: slot}
+pops:
+  - {name: a, servers: 2, latency_ms: 10}
+  - {name: b, servers: 2, latency_ms: 10}
+balancer: {strategy: p2c, global: capacity}
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := common.NewRNG(1)
+	servers := model.InitServers(cfg, rng)
+	b := NewHierarchicalBalancer("p2c", cfg, servers, rng)
+	Attach(b, &Env{Sim: simgo.NewSimulation(), Stats: stats.NewStatistics(cfg)})
+
+	// в a почти все слоты заняты подключёнными сессиями без текущих передач, в b занята половина
+	limit := servers[0].Parameters.MaxConnections
+	servers[0].CurrentSessions = limit - 1
+	servers[1].CurrentSessions = limit - 1
+	servers[2].CurrentSessions = limit / 2
+	servers[3].CurrentSessions = limit / 2
+	if s := b.PickServer(&model.Session{ID: 1}); s.PoP != "b" {
+		t.Fatalf("picked PoP %q, want b", s.PoP)
+	}
+}
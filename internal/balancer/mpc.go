@@ -0,0 +1,167 @@
+package balancer
+
+import (
+	"math"
+	"sync"
+
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/fschuetz04/simgo"
+)
+
+// placement — прогнозируемые моменты окончания сессий, назначенных на сервер,
+// сгруппированные по секундам
+type placement struct {
+	ends   map[int]int // секунда окончания -> кол-во сессий
+	active int         // сессии, которые по прогнозу ещё не завершились
+	cursor int         // первая секунда, которая ещё не истекла
+}
+
+// placed — прогноз, учтённый за сессией
+type placed struct {
+	serverID int
+	end      int
+}
+
+// remove снимает с сервера сессию с прогнозом окончания end, если он ещё не истёк
+func (p *placement) remove(end int) {
+	if end < p.cursor {
+		return
+	}
+	if p.ends[end]--; p.ends[end] == 0 {
+		delete(p.ends, end)
+	}
+	p.active--
+}
+
+// MPCBalancer назначает сессию на сервер, минимизирующий прогнозируемую
+// нагрузку на горизонте с учётом подсказки о длине сессии (по аналогии с SITA):
+// короткие сессии идут туда, где мала текущая нагрузка, длинные — где мала будущая.
+type MPCBalancer struct {
+	servers    []*model.Server
+	cfg        *config.Config
+	horizon    float64
+	meanLength float64 // длина сессии по умолчанию, если подсказки нет
+	sim        *simgo.Simulation
+	mu         sync.Mutex
+	placements map[int]*placement
+	sessions   map[int64]placed // учтённые сессии: при перебросе и окончании прогноз снимается
+}
+
+func NewMPCBalancer(servers []*model.Server, cfg *config.Config) *MPCBalancer {
+	b := &MPCBalancer{
+		servers:    servers,
+		cfg:        cfg,
+		horizon:    cfg.Balancer.MPCHorizon,
+		meanLength: cfg.Traffic.Fragments.Expected(),
+		mu:         sync.Mutex{},
+		placements: make(map[int]*placement, len(servers)),
+		sessions:   make(map[int64]placed),
+	}
+	for _, s := range servers {
+		b.placements[s.ID] = &placement{ends: make(map[int]int)}
+	}
+	return b
+}
+
+func (b *MPCBalancer) Attach(env *Env) {
+	b.sim = env.Sim
+	env.Stats.OnSessionEnd(func(sessionID int64) {
+		b.mu.Lock()
+		b.forget(sessionID)
+		b.mu.Unlock()
+	})
+}
+
+// forget снимает прогноз сессии; вызывается под b.mu
+func (b *MPCBalancer) forget(sessionID int64) {
+	if pl, ok := b.sessions[sessionID]; ok {
+		b.placements[pl.serverID].remove(pl.end)
+		delete(b.sessions, sessionID)
+	}
+}
+
+func (b *MPCBalancer) now() float64 {
+	if b.sim == nil {
+		return 0
+	}
+	return b.sim.Now()
+}
+
+// cost — прирост суммы квадратов утилизации сервера по секундам горизонта
+// при добавлении сессии длительностью steps секунд:
+// sum_k ((l_k+1)^2 - l_k^2) / cap^2 = sum_k (2*l_k + 1) / cap^2
+func (b *MPCBalancer) cost(p *placement, now float64, steps int, capacity float64) float64 {
+	first := int(now)
+	for ; p.cursor < first; p.cursor++ {
+		p.active -= p.ends[p.cursor]
+		delete(p.ends, p.cursor)
+	}
+	load := float64(p.active)
+	cost := 0.0
+	for k := 0; k < steps; k++ {
+		cost += 2*load + 1
+		load -= float64(p.ends[first+k])
+	}
+	return cost / (capacity * capacity)
+}
+
+func (b *MPCBalancer) PickServer(session *model.Session) *model.Server {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	length := session.Hint
+	if length <= 0 {
+		length = b.meanLength
+	}
+	// при перебросе посреди сессии прогнозируется только оставшаяся часть
+	length = max(length-float64(session.Segment), 1)
+	lifetime := length * b.cfg.Cluster.SegmentDuration
+	now := b.now()
+	steps := max(int(math.Ceil(math.Min(lifetime, b.horizon))), 1)
+
+	// прежний прогноз переброшенной сессии не учитывается при выборе
+	prev, moved := b.sessions[session.ID]
+	b.forget(session.ID)
+
+	var best *model.Server
+	bestScore := math.MaxFloat64
+	for _, s := range b.servers {
+		if !s.IsAvailable() || s.IsOverLoaded() {
+			continue
+		}
+		s.Lock()
+		capacity := float64(s.Parameters.MaxConnections)
+		s.Unlock()
+		if capacity <= 0 {
+			continue
+		}
+		score := b.cost(b.placements[s.ID], now, steps, capacity)
+		if score < bestScore {
+			best, bestScore = s, score
+		}
+	}
+	if best == nil {
+		if moved { // сессия остаётся на прежнем сервере
+			b.place(session.ID, prev.serverID, prev.end)
+		}
+		return nil
+	}
+	b.place(session.ID, best.ID, max(int(now+lifetime), b.placements[best.ID].cursor))
+	return best
+}
+
+// place учитывает сессию на сервере с прогнозом окончания end; вызывается под b.mu
+func (b *MPCBalancer) place(sessionID int64, serverID, end int) {
+	p := b.placements[serverID]
+	if end < p.cursor {
+		return
+	}
+	p.ends[end]++
+	p.active++
+	b.sessions[sessionID] = placed{serverID: serverID, end: end}
+}
+
+func (b *MPCBalancer) GetServers() []*model.Server {
+	return b.servers
+}
@@ -0,0 +1,52 @@
+package balancer
+
+import (
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+func TestMPCRoutesByLength(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Cluster.SegmentDuration = 1
+	cfg.Balancer.MPCHorizon = 100
+	servers := []*model.Server{
+		{ID: 1, Parameters: &model.ServerParameters{MaxConnections: 100}},
+		{ID: 2, Parameters: &model.ServerParameters{MaxConnections: 100}},
+	}
+	b := NewMPCBalancer(servers, cfg)
+	st := stats.NewStatistics(cfg)
+	Attach(b, &Env{Sim: simgo.NewSimulation(), Stats: st})
+
+	// на 1-м сервере много сессий, заканчивающихся через 5 с, на 2-м — меньше, но длинных
+	for id := range int64(10) {
+		b.place(100+id, 1, 5)
+	}
+	for id := range int64(6) {
+		b.place(200+id, 2, 100)
+	}
+
+	short := &model.Session{ID: 1, Hint: 2}
+	long := &model.Session{ID: 2, Hint: 50}
+	if s := b.PickServer(short); s.ID != 2 {
+		t.Fatalf("short session went to server %d, want 2 (lower load now)", s.ID)
+	}
+	if s := b.PickServer(long); s.ID != 1 {
+		t.Fatalf("long session went to server %d, want 1 (lower load later)", s.ID)
+	}
+
+	// переброс не учитывает сессию дважды, окончание снимает прогноз
+	long.Segment = 10
+	b.PickServer(long)
+	if n := b.placements[1].active + b.placements[2].active; n != 18 {
+		t.Fatalf("active after redirect = %d, want 18", n)
+	}
+	st.EndSession(long.ID)
+	st.EndSession(short.ID)
+	if a1, a2 := b.placements[1].active, b.placements[2].active; a1 != 10 || a2 != 6 {
+		t.Fatalf("active after end = %d, %d, want 10, 6", a1, a2)
+	}
+}
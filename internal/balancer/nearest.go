@@ -0,0 +1,39 @@
+package balancer
+
+import (
+	"math"
+
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+)
+
+// NearestBalancer выбирает незагруженный сервер с минимальной задержкой до клиента сессии
+type NearestBalancer struct {
+	servers []*model.Server
+	cfg     *config.Config
+}
+
+func NewNearestBalancer(servers []*model.Server, cfg *config.Config) *NearestBalancer {
+	return &NearestBalancer{
+		servers: servers,
+		cfg:     cfg,
+	}
+}
+
+func (b *NearestBalancer) PickServer(session *model.Session) *model.Server {
+	var best *model.Server
+	bestOWD := math.MaxFloat64
+	for _, s := range b.servers {
+		if !s.IsAvailable() || s.IsOverLoaded() {
+			continue
+		}
+		if owd := s.OWDFor(session, b.cfg); owd < bestOWD {
+			best, bestOWD = s, owd
+		}
+	}
+	return best
+}
+
+func (b *NearestBalancer) GetServers() []*model.Server {
+	return b.servers
+}
@@ -21,30 +21,29 @@ func NewP2CBalancer(servers []*model.Server, rng *common.RNG) *P2CBalancer {
 	}
 }
 
-func (b *P2CBalancer) PickServer(_ int64) *model.Server {
+func (b *P2CBalancer) PickServer(_ *model.Session) *model.Server {
 	b.mu.RLock()
-	n := len(b.servers)
+	servers := available(b.servers)
+	n := len(servers)
 	if n == 0 {
 		b.mu.RUnlock()
 		return nil
 	}
+	if n == 1 {
+		b.mu.RUnlock()
+		return servers[0]
+	}
 
 	i1 := b.rng.Intn(n)
 	i2 := b.rng.Intn(n - 1)
 	if i2 >= i1 {
 		i2++
 	}
-	s1, s2 := b.servers[i1], b.servers[i2]
+	s1, s2 := servers[i1], servers[i2]
 	b.mu.RUnlock()
-	s1.Lock()
-	s2.Lock()
-	if s1.CurrentConnections <= s2.CurrentConnections {
-		s1.Unlock()
-		s2.Unlock()
+	if s1.Load() <= s2.Load() {
 		return s1
 	}
-	s1.Unlock()
-	s2.Unlock()
 	return s2
 }
 
@@ -10,19 +10,19 @@ import (
 )
 
 func TestP2CDist(t *testing.T) {
-	n := 10
 	rng := common.NewRNG(42)
 	cfg, err := config.Load("../../config/default.yaml")
 	if err != nil {
 		t.Fatalf("no config")
 	}
 	servers := model.InitServers(cfg, rng)
+	n := len(servers)
 	p2c := NewP2CBalancer(servers, common.NewRNG(42))
 
 	const iter = 1_000_000
 	count := make([]int, n)
 	for i := 0; i < iter; i++ {
-		s := p2c.PickServer(int64(i))
+		s := p2c.PickServer(&model.Session{ID: int64(i)})
 		count[s.ID-1]++
 	}
 	mean := float64(iter) / float64(n)
@@ -4,63 +4,63 @@ import (
 	"math"
 	"sync"
 
-	"github.com/emrzvv/lb-research/internal/metric"
 	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
 )
 
 type PeakEWMABalancer struct {
 	servers     []*model.Server
 	alpha       float64
 	mu          sync.Mutex
-	ewma        []float64 // S_i
-	lastUpdated []float64
+	ewma        map[int]float64 // S_i
+	lastUpdated map[int]float64
 }
 
 func NewPeakEWMABalancer(servers []*model.Server, alpha float64) *PeakEWMABalancer {
-	metric.RTTChan = make(chan metric.RequestRTT, 1<<14)
-
 	p := &PeakEWMABalancer{
 		servers:     servers,
 		alpha:       alpha,
 		mu:          sync.Mutex{},
-		ewma:        make([]float64, len(servers)),
-		lastUpdated: make([]float64, len(servers)),
+		ewma:        make(map[int]float64, len(servers)),
+		lastUpdated: make(map[int]float64, len(servers)),
+	}
+	for _, s := range servers {
+		p.ewma[s.ID] = 0
 	}
-	go p.collect()
 	return p
 }
 
-func (b *PeakEWMABalancer) collect() {
-	for ev := range metric.RTTChan {
-		idx := ev.ServerID - 1
+func (b *PeakEWMABalancer) Attach(env *Env) {
+	env.Stats.OnRequest(b.collect)
+}
 
-		b.mu.Lock()
-		prev := b.ewma[idx]
-		peak := max(prev, ev.RTT)
+func (b *PeakEWMABalancer) collect(ev *stats.RequestEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-		b.ewma[idx] = b.alpha*peak + (1-b.alpha)*prev
-		b.lastUpdated[idx] = ev.When
-		b.mu.Unlock()
+	prev, ok := b.ewma[ev.ServerID]
+	if !ok {
+		return
 	}
+	peak := max(prev, ev.Duration)
+
+	b.ewma[ev.ServerID] = b.alpha*peak + (1-b.alpha)*prev
+	b.lastUpdated[ev.ServerID] = ev.T1
 }
 
-func (b *PeakEWMABalancer) PickServer(sessionID int64) *model.Server {
+func (b *PeakEWMABalancer) PickServer(session *model.Session) *model.Server {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	best, bestScore := -1, math.MaxFloat64
-	for i, s := range b.servers {
-		s.Lock()
-		score := b.ewma[i] * float64(s.CurrentConnections+1)
+	var best *model.Server
+	bestScore := math.MaxFloat64
+	for _, s := range available(b.servers) {
+		score := b.ewma[s.ID] * float64(s.Load()+1)
 		if score < bestScore {
-			best, bestScore = i, score
+			best, bestScore = s, score
 		}
-		s.Unlock()
-	}
-	if best >= 0 {
-		return b.servers[best]
 	}
-	return nil
+	return best
 }
 
 func (b *PeakEWMABalancer) GetServers() []*model.Server {
@@ -0,0 +1,102 @@
+package balancer
+
+import (
+	"sync"
+
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+// PredictiveBalancer прогнозирует интенсивность поступлений методом Холта
+// и заранее переключается с affinity-стратегии на spread-стратегию,
+// если прогноз на горизонте превышает порог.
+type PredictiveBalancer struct {
+	affinity  Balancer
+	spread    Balancer
+	cfg       *config.Config
+	mu        sync.Mutex
+	arrivals  int // поступления в текущем бине
+	level     float64
+	trend     float64
+	started   bool
+	spreading bool
+}
+
+func NewPredictiveBalancer(affinity, spread Balancer, cfg *config.Config) *PredictiveBalancer {
+	return &PredictiveBalancer{
+		affinity: affinity,
+		spread:   spread,
+		cfg:      cfg,
+		mu:       sync.Mutex{},
+	}
+}
+
+func (b *PredictiveBalancer) Attach(env *Env) {
+	Attach(b.affinity, env)
+	Attach(b.spread, env)
+	env.Stats.OnArrival(func(*stats.ArrivalEvent) {
+		b.mu.Lock()
+		b.arrivals++
+		b.mu.Unlock()
+	})
+	env.Sim.Process(func(proc simgo.Process) { b.forecast(proc, env) })
+}
+
+// forecast на конце каждого бина обновляет уровень и тренд и пересчитывает режим
+func (b *PredictiveBalancer) forecast(proc simgo.Process, env *Env) {
+	bin := b.cfg.Balancer.PredBin
+	alpha, beta := b.cfg.Balancer.PredAlpha, b.cfg.Balancer.PredBeta
+	steps := b.cfg.Balancer.PredHorizon / bin
+
+	for proc.Now() < b.cfg.Simulation.TimeSeconds {
+		proc.Wait(proc.Timeout(bin))
+
+		b.mu.Lock()
+		actual := float64(b.arrivals) / bin
+		b.arrivals = 0
+		if !b.started {
+			b.level, b.trend, b.started = actual, 0, true
+		} else {
+			prev := b.level
+			b.level = alpha*actual + (1-alpha)*(b.level+b.trend)
+			b.trend = beta*(b.level-prev) + (1-beta)*b.trend
+		}
+		predicted := b.level + steps*b.trend
+		limit := b.cfg.Balancer.PredThreshold * b.cfg.Traffic.BaseRPS
+		if env.Rate != nil {
+			limit = b.cfg.Balancer.PredThreshold * env.Rate.Base()
+		}
+		b.spreading = predicted > limit || actual > limit
+		spreading := b.spreading
+		b.mu.Unlock()
+
+		env.Stats.AddForecast(&stats.ForecastEvent{
+			T:         proc.Now(),
+			Actual:    actual,
+			Forecast:  predicted,
+			Spreading: spreading,
+		})
+	}
+}
+
+func (b *PredictiveBalancer) PickServer(session *model.Session) *model.Server {
+	b.mu.Lock()
+	spreading := b.spreading
+	b.mu.Unlock()
+
+	first, second := b.affinity, b.spread
+	if spreading {
+		first, second = b.spread, b.affinity
+	}
+	// если основная стратегия не нашла сервер, пробуем другую
+	if s := first.PickServer(session); s != nil {
+		return s
+	}
+	return second.PickServer(session)
+}
+
+func (b *PredictiveBalancer) GetServers() []*model.Server {
+	return b.affinity.GetServers()
+}
@@ -0,0 +1,115 @@
+package balancer
+
+import (
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+// countingBalancer считает выборы обёрнутой стратегии
+type countingBalancer struct {
+	Balancer
+	picks int
+}
+
+func (b *countingBalancer) PickServer(session *model.Session) *model.Server {
+	b.picks++
+	return b.Balancer.PickServer(session)
+}
+
+func TestPredictiveSwitchesAheadOfRise(t *testing.T) {
+	rng := common.NewRNG(42)
+	cfg, err := config.Load("../../config/default.yaml")
+	if err != nil {
+		t.Fatalf("no config")
+	}
+	servers := model.InitServers(cfg, rng)
+	cfg.Simulation.TimeSeconds = 60
+	cfg.Traffic.BaseRPS = 100
+	cfg.Balancer.PredBin = 1
+	cfg.Balancer.PredAlpha = 0.5
+	cfg.Balancer.PredBeta = 0.5
+	cfg.Balancer.PredHorizon = 5
+	cfg.Balancer.PredThreshold = 2
+
+	ch := &countingBalancer{Balancer: NewCHBalancer(servers, cfg.Balancer.CHReplicas)}
+	p2c := &countingBalancer{Balancer: NewP2CBalancer(servers, rng)}
+	b := NewPredictiveBalancer(ch, p2c, cfg)
+
+	sim := simgo.NewSimulation()
+	st := stats.NewStatistics(cfg)
+	Attach(b, &Env{Sim: sim, Stats: st})
+
+	// base_rps до t = 20, затем рост на base_rps каждые 10 с: порог 2·base_rps — в t = 30
+	var chBefore, p2cAfter int
+	sim.Process(func(proc simgo.Process) {
+		for t := 0; t < 60; t++ {
+			rate := cfg.Traffic.BaseRPS * (1 + max(0, float64(t)-20)/10)
+			for range int(rate) {
+				st.AddArrival(&stats.ArrivalEvent{T: proc.Now()})
+			}
+			switch t {
+			case 10:
+				b.PickServer(&model.Session{ID: 1})
+				chBefore = ch.picks
+			case 45:
+				b.PickServer(&model.Session{ID: 1})
+				p2cAfter = p2c.picks
+			}
+			proc.Wait(proc.Timeout(1))
+		}
+	})
+	sim.RunUntil(cfg.Simulation.TimeSeconds)
+	sim.Shutdown()
+
+	if chBefore != 1 || p2cAfter != 1 {
+		t.Fatalf("picks: ch before rise = %d, p2c after rise = %d, want 1 and 1", chBefore, p2cAfter)
+	}
+	switched := -1.0
+	for _, f := range st.Forecasts {
+		if f.Spreading {
+			switched = f.T
+			break
+		}
+	}
+	// прогноз по тренду пересекает порог раньше фактической интенсивности
+	if switched <= 20 || switched >= 30 {
+		t.Fatalf("switched to spread at %v, want within (20, 30)", switched)
+	}
+}
+
+// emptyBalancer не находит сервер
+type emptyBalancer struct {
+	Balancer
+}
+
+func (emptyBalancer) PickServer(*model.Session) *model.Server {
+	return nil
+}
+
+func TestPredictiveFallsBackBothWays(t *testing.T) {
+	rng := common.NewRNG(42)
+	cfg, err := config.Load("../../config/default.yaml")
+	if err != nil {
+		t.Fatalf("no config")
+	}
+	servers := model.InitServers(cfg, rng)
+	p2c := NewP2CBalancer(servers, rng)
+	none := emptyBalancer{p2c}
+
+	for _, spreading := range []bool{false, true} {
+		affinity, spread := Balancer(none), Balancer(p2c)
+		if spreading {
+			affinity, spread = p2c, none
+		}
+		b := NewPredictiveBalancer(affinity, spread, cfg)
+		b.spreading = spreading
+		if b.PickServer(&model.Session{ID: 1}) == nil {
+			t.Fatalf("spreading = %v: no server although the other strategy has one", spreading)
+		}
+	}
+}
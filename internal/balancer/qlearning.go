@@ -0,0 +1,214 @@
+package balancer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+)
+
+const (
+	qlCandidates = 2 // действие — выбор одного из двух случайных серверов
+	qlSpikeFlags = 2
+)
+
+// qlRateBounds — границы корзин отношения текущей интенсивности к базовой
+var qlRateBounds = []float64{1.5, 3, 6}
+
+// QTable — табличная Q-функция.
+// Состояние: (корзина утилизации 1-го кандидата, 2-го кандидата, корзина интенсивности, флаг всплеска).
+type QTable struct {
+	UtilBuckets int       `json:"util_buckets"`
+	RateBuckets int       `json:"rate_buckets"`
+	Actions     int       `json:"actions"`
+	Q           []float64 `json:"q"`
+}
+
+func NewQTable(utilBuckets int) *QTable {
+	rateBuckets := len(qlRateBounds) + 1
+	states := utilBuckets * utilBuckets * rateBuckets * qlSpikeFlags
+	return &QTable{
+		UtilBuckets: utilBuckets,
+		RateBuckets: rateBuckets,
+		Actions:     qlCandidates,
+		Q:           make([]float64, states*qlCandidates),
+	}
+}
+
+func LoadQTable(path string) (*QTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load q-table: %w", err)
+	}
+	var t QTable
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("cannot parse q-table %s: %w", path, err)
+	}
+	want := t.UtilBuckets * t.UtilBuckets * t.RateBuckets * qlSpikeFlags * t.Actions
+	if t.Actions != qlCandidates || t.RateBuckets != len(qlRateBounds)+1 || len(t.Q) != want {
+		return nil, fmt.Errorf("q-table %s has incompatible shape", path)
+	}
+	return &t, nil
+}
+
+func (t *QTable) Save(path string) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (t *QTable) state(u1, u2, rate int, spike bool) int {
+	s := ((u1*t.UtilBuckets+u2)*t.RateBuckets + rate) * qlSpikeFlags
+	if spike {
+		s++
+	}
+	return s
+}
+
+func (t *QTable) best(state int) (int, float64) {
+	row := t.Q[state*t.Actions : (state+1)*t.Actions]
+	best, bestQ := 0, row[0]
+	for a, q := range row[1:] {
+		if q > bestQ {
+			best, bestQ = a+1, q
+		}
+	}
+	return best, bestQ
+}
+
+type decision struct {
+	state      int
+	action     int
+	serverID   int
+	candidates [qlCandidates]*model.Server
+}
+
+type QLearningBalancer struct {
+	servers  []*model.Server
+	rng      *common.RNG
+	table    *QTable
+	alpha    float64
+	gamma    float64
+	epsilon  float64
+	training bool
+	rate     RateSource
+	mu       sync.Mutex
+	pending  map[int64]decision // решения, ожидающие исхода первого запроса сессии
+}
+
+func NewQLearningBalancer(
+	servers []*model.Server,
+	cfg *config.Config,
+	rng *common.RNG,
+	table *QTable,
+	training bool) *QLearningBalancer {
+
+	return &QLearningBalancer{
+		servers:  servers,
+		rng:      rng,
+		table:    table,
+		alpha:    cfg.Balancer.QLAlpha,
+		gamma:    cfg.Balancer.QLGamma,
+		epsilon:  cfg.Balancer.QLEpsilon,
+		training: training,
+		mu:       sync.Mutex{},
+		pending:  make(map[int64]decision),
+	}
+}
+
+func (b *QLearningBalancer) Attach(env *Env) {
+	b.rate = env.Rate
+	if !b.training {
+		return
+	}
+	env.Stats.OnRequest(func(re *stats.RequestEvent) {
+		b.reward(re.SessiontID, re.ServerID, 1)
+	})
+	env.Stats.OnDrop(func(de *stats.DropEvent) {
+		b.reward(de.SessionID, de.ServerID, -1)
+	})
+}
+
+func (b *QLearningBalancer) utilBucket(s *model.Server) int {
+	u := float64(s.Load()) / float64(max(s.Parameters.MaxConnections, 1))
+	return min(int(u*float64(b.table.UtilBuckets)), b.table.UtilBuckets-1)
+}
+
+func (b *QLearningBalancer) rateBucket() (int, bool) {
+	if b.rate == nil {
+		return 0, false
+	}
+	ratio := b.rate.Get() / b.rate.Base()
+	bucket := 0
+	for bucket < len(qlRateBounds) && ratio >= qlRateBounds[bucket] {
+		bucket++
+	}
+	return bucket, ratio > 1
+}
+
+func (b *QLearningBalancer) PickServer(session *model.Session) *model.Server {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	servers := available(b.servers)
+	n := len(servers)
+	if n == 0 {
+		return nil
+	}
+	if n == 1 {
+		return servers[0]
+	}
+	i1 := b.rng.Intn(n)
+	i2 := b.rng.Intn(n - 1)
+	if i2 >= i1 {
+		i2++
+	}
+	candidates := [qlCandidates]*model.Server{servers[i1], servers[i2]}
+	state := b.observe(candidates)
+
+	action, _ := b.table.best(state)
+	if b.training {
+		if b.rng.Float64() < b.epsilon {
+			action = b.rng.Intn(qlCandidates)
+		}
+		b.pending[session.ID] = decision{
+			state: state, action: action, serverID: candidates[action].ID, candidates: candidates}
+	}
+	return candidates[action]
+}
+
+// observe — состояние для пары кандидатов в текущий момент
+func (b *QLearningBalancer) observe(candidates [qlCandidates]*model.Server) int {
+	rate, spike := b.rateBucket()
+	return b.table.state(b.utilBucket(candidates[0]), b.utilBucket(candidates[1]), rate, spike)
+}
+
+// reward обновляет Q(s, a) по первому исходу запроса после выбора сервера:
+// Q(s,a) += alpha * (r + gamma * max_a' Q(s',a') - Q(s,a)), s' — состояние тех же
+// кандидатов в момент исхода
+func (b *QLearningBalancer) reward(sessionID int64, serverID int, r float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	d, ok := b.pending[sessionID]
+	if !ok || d.serverID != serverID {
+		return
+	}
+	delete(b.pending, sessionID)
+
+	_, next := b.table.best(b.observe(d.candidates))
+	idx := d.state*b.table.Actions + d.action
+	q := b.table.Q[idx]
+	b.table.Q[idx] = q + b.alpha*(r+b.gamma*next-q)
+}
+
+func (b *QLearningBalancer) GetServers() []*model.Server {
+	return b.servers
+}
@@ -10,8 +10,12 @@ type RandomBalancer struct {
 	rng     *common.RNG
 }
 
-func (b *RandomBalancer) PickServer(sessionID int64) *model.Server {
-	return b.servers[b.rng.Intn(len(b.servers))]
+func (b *RandomBalancer) PickServer(session *model.Session) *model.Server {
+	servers := available(b.servers)
+	if len(servers) == 0 {
+		return nil
+	}
+	return servers[b.rng.Intn(len(servers))]
 }
 
 func (b *RandomBalancer) GetServers() []*model.Server {
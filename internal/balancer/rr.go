@@ -12,11 +12,15 @@ type RRBalancer struct {
 	idx     int
 }
 
-func (b *RRBalancer) PickServer(sessionID int64) *model.Server {
+func (b *RRBalancer) PickServer(session *model.Session) *model.Server {
+	servers := available(b.servers)
+	if len(servers) == 0 {
+		return nil
+	}
 	b.mu.Lock()
-	b.idx = (b.idx + 1) % len(b.servers)
-	b.mu.Unlock()
-	return b.servers[b.idx]
+	defer b.mu.Unlock()
+	b.idx = (b.idx + 1) % len(servers)
+	return servers[b.idx]
 }
 
 func (b *RRBalancer) GetServers() []*model.Server {
@@ -0,0 +1,48 @@
+package balancer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/emrzvv/lb-research/internal/model"
+)
+
+// SwitchBalancer переключает стратегию во время прогона (scenario: strategy).
+// Все стратегии строятся заранее и подключаются к событиям с начала прогона,
+// так что накопленное состояние (EWMA, апостериорные) к переключению уже есть.
+type SwitchBalancer struct {
+	mu         sync.RWMutex
+	current    string
+	names      []string // порядок подключения
+	strategies map[string]Balancer
+}
+
+func (b *SwitchBalancer) Switch(strategy string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.strategies[strategy]; !ok {
+		return fmt.Errorf("strategy %q was not built", strategy)
+	}
+	b.current = strategy
+	return nil
+}
+
+func (b *SwitchBalancer) PickServer(session *model.Session) *model.Server {
+	b.mu.RLock()
+	cur := b.strategies[b.current]
+	b.mu.RUnlock()
+	return cur.PickServer(session)
+}
+
+func (b *SwitchBalancer) GetServers() []*model.Server {
+	b.mu.RLock()
+	cur := b.strategies[b.current]
+	b.mu.RUnlock()
+	return cur.GetServers()
+}
+
+func (b *SwitchBalancer) Attach(env *Env) {
+	for _, name := range b.names {
+		Attach(b.strategies[name], env)
+	}
+}
@@ -0,0 +1,153 @@
+package balancer
+
+import (
+	"math"
+	"sync"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// arm — апостериорное Beta(1+successes, 1+failures) одного сервера.
+// При забывании счётчики умножаются на discount^(step-seen) лениво, в момент обращения.
+type arm struct {
+	successes float64
+	failures  float64
+	seen      int64
+	window    []bool // последние исходы (скользящее окно), true — успех
+	next      int
+}
+
+type ThompsonBalancer struct {
+	servers   []*model.Server
+	rng       *common.RNG
+	cfg       *config.Config
+	threshold float64
+	discount  float64
+	window    int
+	mu        sync.Mutex
+	arms      map[int]*arm
+	step      int64 // номер наблюдения, для ленивого забывания
+}
+
+func NewThompsonBalancer(servers []*model.Server, cfg *config.Config, rng *common.RNG) *ThompsonBalancer {
+	b := &ThompsonBalancer{
+		servers:   servers,
+		rng:       rng,
+		cfg:       cfg,
+		threshold: cfg.Balancer.TSThreshold,
+		discount:  cfg.Balancer.TSDiscount,
+		window:    cfg.Balancer.TSWindow,
+		mu:        sync.Mutex{},
+		arms:      make(map[int]*arm, len(servers)),
+	}
+	for _, s := range servers {
+		b.arms[s.ID] = &arm{}
+	}
+	return b
+}
+
+func (b *ThompsonBalancer) Attach(env *Env) {
+	env.Stats.OnRequest(func(re *stats.RequestEvent) {
+		// штраф редиректа не относится к серверу, на который перенесли сессию
+		b.observe(re.ServerID, re.Queued+re.Service <= b.threshold)
+	})
+	env.Stats.OnDrop(func(de *stats.DropEvent) {
+		if de.ServerID != 0 {
+			b.observe(de.ServerID, false)
+		}
+	})
+	env.Sim.Process(func(proc simgo.Process) { b.trace(proc, env.Stats) })
+}
+
+// decay применяет накопленное забывание к счётчикам
+func (b *ThompsonBalancer) decay(a *arm) {
+	if b.window > 0 || b.discount >= 1 {
+		return
+	}
+	if d := b.step - a.seen; d > 0 {
+		f := math.Pow(b.discount, float64(d))
+		a.successes *= f
+		a.failures *= f
+	}
+	a.seen = b.step
+}
+
+func (b *ThompsonBalancer) observe(serverID int, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	a, ok := b.arms[serverID]
+	if !ok {
+		return
+	}
+	b.step++
+	if b.window > 0 {
+		if len(a.window) < b.window {
+			a.window = append(a.window, success)
+		} else {
+			old := a.window[a.next]
+			if old {
+				a.successes--
+			} else {
+				a.failures--
+			}
+			a.window[a.next] = success
+			a.next = (a.next + 1) % b.window
+		}
+	} else {
+		b.decay(a)
+	}
+	if success {
+		a.successes++
+	} else {
+		a.failures++
+	}
+}
+
+func (b *ThompsonBalancer) posterior(a *arm) (float64, float64) {
+	b.decay(a)
+	return 1 + a.successes, 1 + a.failures
+}
+
+func (b *ThompsonBalancer) PickServer(session *model.Session) *model.Server {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var best *model.Server
+	bestScore := -1.0
+	for _, s := range b.servers {
+		if !s.IsAvailable() || s.IsOverLoaded() {
+			continue
+		}
+		alpha, beta := b.posterior(b.arms[s.ID])
+		score := distuv.Beta{Alpha: alpha, Beta: beta, Src: b.rng}.Rand()
+		if score > bestScore {
+			best, bestScore = s, score
+		}
+	}
+	return best
+}
+
+func (b *ThompsonBalancer) GetServers() []*model.Server {
+	return b.servers
+}
+
+// trace периодически сохраняет апостериорные параметры всех серверов
+func (b *ThompsonBalancer) trace(proc simgo.Process, st *stats.Statistics) {
+	step := b.cfg.Simulation.StepSeconds
+	for t := 0.0; t < b.cfg.Simulation.TimeSeconds; t += step {
+		proc.Wait(proc.Timeout(step))
+		now := proc.Now()
+		b.mu.Lock()
+		for _, s := range b.servers {
+			alpha, beta := b.posterior(b.arms[s.ID])
+			st.AddPosterior(&stats.PosteriorEvent{T: now, ServerID: s.ID, Alpha: alpha, Beta: beta})
+		}
+		b.mu.Unlock()
+	}
+}
@@ -0,0 +1,68 @@
+package balancer
+
+import (
+	"math"
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+func TestThompsonPosterior(t *testing.T) {
+	rng := common.NewRNG(42)
+	cfg, err := config.Load("../../config/default.yaml")
+	if err != nil {
+		t.Fatalf("no config")
+	}
+	servers := model.InitServers(cfg, rng)
+
+	cfg.Balancer.TSWindow = 4
+	ts := NewThompsonBalancer(servers, cfg, rng)
+	for _, ok := range []bool{true, true, false, true, false, false} {
+		ts.observe(servers[0].ID, ok)
+	}
+	// в окне остались: false, true, false, false
+	alpha, beta := ts.posterior(ts.arms[servers[0].ID])
+	if alpha != 2 || beta != 4 {
+		t.Fatalf("window posterior = (%v, %v), want (2, 4)", alpha, beta)
+	}
+
+	cfg.Balancer.TSWindow = 0
+	cfg.Balancer.TSDiscount = 0.5
+	ts = NewThompsonBalancer(servers, cfg, rng)
+	ts.observe(servers[0].ID, true)
+	ts.observe(servers[1].ID, false)
+	ts.observe(servers[1].ID, false)
+	alpha, beta = ts.posterior(ts.arms[servers[0].ID])
+	if math.Abs(alpha-1.25) > 1e-9 || beta != 1 {
+		t.Fatalf("discounted posterior = (%v, %v), want (1.25, 1)", alpha, beta)
+	}
+}
+
+func TestThompsonIgnoresRedirectPenalty(t *testing.T) {
+	rng := common.NewRNG(42)
+	cfg, err := config.Load("../../config/default.yaml")
+	if err != nil {
+		t.Fatalf("no config")
+	}
+	servers := model.InitServers(cfg, rng)
+	cfg.Balancer.TSWindow = 0
+	cfg.Balancer.TSDiscount = 1
+	ts := NewThompsonBalancer(servers, cfg, rng)
+	st := stats.NewStatistics(cfg)
+	Attach(ts, &Env{Sim: simgo.NewSimulation(), Stats: st})
+
+	// первый запрос после редиректа: штраф в duration, обслуживание быстрое
+	st.AddRequest(&stats.RequestEvent{ServerID: servers[0].ID, Duration: 100.2, Service: 0.2})
+	// медленный сервер: очередь и обслуживание дольше порога
+	st.AddRequest(&stats.RequestEvent{ServerID: servers[1].ID, Duration: 9, Queued: 4, Service: 5})
+	if a := ts.arms[servers[0].ID]; a.successes != 1 || a.failures != 0 {
+		t.Fatalf("redirected request: arm = %+v, want a success", a)
+	}
+	if a := ts.arms[servers[1].ID]; a.successes != 0 || a.failures != 1 {
+		t.Fatalf("slow request: arm = %+v, want a failure", a)
+	}
+}
@@ -24,17 +24,18 @@ func NewWLCBalancer(servers []*model.Server) *WLCBalancer {
 	}
 }
 
-func (b *WLCBalancer) PickServer(sessionID int64) *model.Server {
+func (b *WLCBalancer) PickServer(session *model.Session) *model.Server {
 	b.mu.Lock()
 	toSort := make([]*sorter, 0)
-	for _, s := range b.servers {
-		s.Lock()
-		c := float64(s.CurrentConnections)
+	for _, s := range available(b.servers) {
+		c := float64(s.Load())
 		w := s.Parameters.Mbps
-		s.Unlock()
 		toSort = append(toSort, &sorter{value: c / w, server: s})
 	}
 	b.mu.Unlock()
+	if len(toSort) == 0 {
+		return nil
+	}
 	sort.Slice(toSort, func(i, j int) bool { return toSort[i].value < toSort[j].value })
 	result := toSort[0].server
 	if result.IsOverLoaded() {
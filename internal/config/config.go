@@ -3,8 +3,12 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/emrzvv/lb-research/internal/common"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,13 +21,73 @@ type Config struct {
 
 	Traffic struct {
 		BaseRPS     float64 `yaml:"base_rps"`     // rps, \lambda Пуассона
-		UsersAmount int64   `yaml:"users_amount"` // кол-во возможных уникальных пользователей (сессий)
+		UsersAmount int64   `yaml:"users_amount"` // кол-во возможных уникальных пользователей
+		LengthHint  string  `yaml:"length_hint"`  // подсказка длины сессии балансировщику: none, exact, noisy
+		HintCV      float64 `yaml:"hint_cv"`      // CV лог-нормального шума подсказки (для noisy)
+		Fragments   Dist    `yaml:"fragments"`    // кол-во .ts-фрагментов в сессии (по умолчанию — смесь равномерных)
+
+		// модель пользователей: session_id уникален для каждой сессии, хеширующие
+		// балансировщики ключуются по user_id
+		Users struct {
+			Zipf          float64 `yaml:"zipf"`           // показатель Zipf активности пользователей (0 — равномерно)
+			ReturnP       float64 `yaml:"return_p"`       // вероятность, что сессию открывает вернувшийся пользователь
+			MaxConcurrent int     `yaml:"max_concurrent"` // предел одновременных сессий пользователя (0 — без предела)
+		} `yaml:"users"`
+
+		// каталог контента: сессия смотрит конкретный ролик, её длина — длина ролика
+		Catalog struct {
+			Items  int     `yaml:"items"`  // кол-во роликов (0 — каталога нет, длина сессии из fragments)
+			Zipf   float64 `yaml:"zipf"`   // показатель Zipf популярности (0 — равномерно)
+			Length Dist    `yaml:"length"` // длина ролика во фрагментах (по умолчанию — fragments)
+		} `yaml:"catalog"`
+
+		// профиль интенсивности: base_rps × diurnal × ramps × spikes(mul) + base_rps × spikes(add)
+		Profile struct {
+			Diurnal struct {
+				Amplitude float64 `yaml:"amplitude"` // относительная амплитуда синусоиды, [0, 1) (0 — нет)
+				Period    float64 `yaml:"period_s"`  // период, сек (по умолчанию сутки)
+				Phase     float64 `yaml:"phase_s"`   // сдвиг, сек
+			} `yaml:"diurnal"`
+			// кусочно-линейный множитель по точкам (до первой и после последней — постоянный)
+			Ramps []struct {
+				At     float64 `yaml:"at"`
+				Factor float64 `yaml:"factor"`
+			} `yaml:"ramps"`
+		} `yaml:"profile"`
+
+		// процесс поступлений; средняя интенсивность задаётся профилем (кроме mmpp)
+		Arrivals struct {
+			Process string `yaml:"process"` // poisson | mmpp | hawkes | batch | pareto
+			// mmpp: состояния с множителем интенсивности и экспоненциальным временем пребывания;
+			// следующее состояние выбирается равновероятно из остальных
+			MMPP []struct {
+				Factor   float64 `yaml:"factor"`
+				Duration float64 `yaml:"mean_duration_s"`
+			} `yaml:"mmpp"`
+			Branching float64 `yaml:"branching"`  // hawkes: среднее число порождённых сессий на сессию, [0, 1)
+			Decay     float64 `yaml:"decay_s"`    // hawkes: постоянная затухания возбуждения, сек
+			BatchSize Dist    `yaml:"batch_size"` // batch: число сессий в пачке
+			Shape     float64 `yaml:"shape"`      // pareto: параметр хвоста интервалов, > 1
+		} `yaml:"arrivals"`
+
+		// воспроизведение трассы вместо генерации сессий (профиль и spikes не действуют)
+		Trace struct {
+			File   string  `yaml:"file"`   // csv или jsonl: time_s|timestamp, session_id, user_id, content_id, fragments|segments, region
+			Rebase bool    `yaml:"rebase"` // отсчитывать время от первой записи трассы
+			Speed  float64 `yaml:"speed"`  // ускорение воспроизведения (по умолчанию 1)
+
+			Arrivals     []TraceArrival `yaml:"-"`
+			MaxSessionID int64          `yaml:"-"` // наибольший session_id трассы: присвоенные номера идут после него
+		} `yaml:"trace"`
 	} `yaml:"traffic"`
 
+	// всплески нагрузки; пересекающиеся всплески складываются (add) или перемножаются (mul)
 	Spikes []struct {
 		At       float64 `yaml:"at"`       // секунда старта
 		Duration float64 `yaml:"duration"` // длительность
-		Factor   float64 `yaml:"factor"`   // множитель к base_rps
+		Factor   float64 `yaml:"factor"`   // mul: множитель к интенсивности; add: добавка в долях base_rps
+		Mode     string  `yaml:"mode"`     // mul (по умолчанию) | add
+		Decay    float64 `yaml:"decay_s"`  // постоянная экспоненциального спада после всплеска, сек (0 — спад сразу)
 	} `yaml:"spikes"`
 
 	Cluster struct {
@@ -40,10 +104,161 @@ type Config struct {
 
 		SigmaServer float64 `yaml:"sigma_server"` // CV лог-нормального шума
 
+		// модель пропускной способности: fixed — каждая передача идёт на полной скорости сервера,
+		// ps — активные передачи делят пропускную способность поровну (processor sharing)
+		BandwidthModel string `yaml:"bandwidth_model"`
+
+		// очередь запросов сервера при занятых соединениях
+		Queue struct {
+			Size       int     `yaml:"size"`       // макс. длина очереди (0 — без очереди, сразу отказ max_conn)
+			Discipline string  `yaml:"discipline"` // fifo, lifo, priority (сначала сессии с большим номером фрагмента)
+			Timeout    float64 `yaml:"timeout_s"`  // макс. ожидание в очереди, сек (0 — без ограничения)
+		} `yaml:"queue"`
+
+		// сессии, подключённые к серверу (keep-alive) от первого запроса до окончания или переброса
+		Sessions struct {
+			Mode    string  `yaml:"mode"`         // none — соединения занимают только передачи; slot — сессия держит соединение всё время жизни
+			Reserve float64 `yaml:"reserve_mbps"` // резерв пропускной способности на подключённую сессию (для slot)
+		} `yaml:"sessions"`
+
+		// кэш фрагментов на сервере; при промахе фрагмент сначала загружается с origin
+		Cache struct {
+			Policy     string  `yaml:"policy"`         // lru, lfu, s3fifo
+			Bytes      float64 `yaml:"capacity_bytes"` // ёмкость кэша сервера, байт (0 — кэша нет)
+			OriginRTT  float64 `yaml:"origin_rtt_ms"`  // задержка до origin, мс
+			OriginMbps float64 `yaml:"origin_mbps"`    // канал к origin, общий для всех серверов (processor sharing)
+		} `yaml:"cache"`
+
+		// распределения параметров серверов; по умолчанию строятся из полей выше:
+		// normal(cap_mean_mbps, cap_cv), gamma(owd_mean, owd_cv), lognormal(0, sigma_server)
+		Capacity     Dist `yaml:"capacity"`      // mbps пропускная способность сервера
+		OWD          Dist `yaml:"owd"`           // one-way delay сервера, мс (и его джиттер)
+		ServiceNoise Dist `yaml:"service_noise"` // множитель ко времени передачи фрагмента
+		// штраф к длительности первого запроса после переброса на другой сервер, сек
+		RedirectPenalty Dist `yaml:"redirect_penalty"`
+
 		MaxRetriesPerSegment  int `yaml:"max_retries"`  // количество попыток запросить один и тот же .ts без смены сервера
 		MaxSwitchesPerSession int `yaml:"max_switches"` // сколько раз можем менять сервер во время получения одного видео
+
+		// пулы разнородного оборудования; при задании cluster.servers = сумма count,
+		// незаданные параметры пула берутся из cluster
+		Pools []struct {
+			Name     string   `yaml:"name"`
+			Count    int      `yaml:"count"`         // кол-во серверов в пуле
+			CapMean  float64  `yaml:"cap_mean_mbps"` // mbps средняя пропускная способность
+			CapCV    float64  `yaml:"cap_cv"`        // относительное ст. отклонение пропускной способности
+			MinMbps  float64  `yaml:"min_mbps"`      // ограничение снизу на пропускную способность сервера
+			MaxMbps  float64  `yaml:"max_mbps"`      // ограничение сверху (0 — без ограничения)
+			OWDMean  float64  `yaml:"owd_mean"`      // среднее one-way delay, мс
+			OWDCV    float64  `yaml:"owd_cv"`        // относительное ст. отклонение OWD
+			Capacity Dist     `yaml:"capacity"`      // по умолчанию normal(cap_mean_mbps, cap_cv)
+			OWD      Dist     `yaml:"owd"`           // по умолчанию gamma(owd_mean, owd_cv)
+			Bitrate  float64  `yaml:"bitrate"`       // mbps на поток для расчёта max_conn
+			Labels   []string `yaml:"labels"`        // метки серверов пула (поколение, стойка, ...)
+			PoP      string   `yaml:"pop"`           // PoP серверов пула (если заданы pops)
+		} `yaml:"pools"`
 	} `yaml:"cluster"`
 
+	// сценарий: действия в заданные моменты времени (порядок в списке не важен)
+	Scenario []struct {
+		At       float64 `yaml:"at"`         // секунда действия
+		Action   string  `yaml:"action"`     // fail, recover, drain, capacity, owd, traffic, strategy
+		Servers  []int   `yaml:"servers"`    // id серверов для fail, recover, drain, capacity, owd (пусто — все)
+		Duration float64 `yaml:"duration"`   // через сколько секунд отменить действие (0 — не отменять)
+		Factor   float64 `yaml:"factor"`     // capacity: множитель к mbps и max_conn; traffic: множитель к base_rps
+		Value    float64 `yaml:"value"`      // owd: добавка к OWD серверов, мс
+		Strategy string  `yaml:"strategy"`   // strategy: стратегия балансировки (отмена — balancer.strategy)
+		Deadline float64 `yaml:"deadline_s"` // drain: через сколько секунд переносить подключённые сессии (0 — ждать их окончания)
+		Domain   string  `yaml:"domain"`     // домен отказа вместо servers
+	} `yaml:"scenario"`
+
+	// домены отказа (стойки, линии питания): сервер входит в домен по id, меткам пула
+	// или PoP; отказ и сетевой всплеск домена затрагивают все его серверы сразу
+	Domains []struct {
+		Name       string   `yaml:"name"`
+		Servers    []int    `yaml:"servers"`          // id серверов
+		Labels     []string `yaml:"labels"`           // метки cluster.pools
+		PoP        string   `yaml:"pop"`              // все серверы PoP
+		MTBF       float64  `yaml:"mtbf_s"`           // среднее время между отказами домена, сек (0 — нет)
+		MTTR       float64  `yaml:"mttr_s"`           // среднее время восстановления, сек (по умолчанию failures.mttr_s)
+		SpikeP     float64  `yaml:"spike_prob"`       // вероятность всплеска OWD домена на тике jitter
+		SpikeExtra float64  `yaml:"spike_extra"`      // +мс при всплеске (по умолчанию jitter.spike_extra)
+		SpikeDur   float64  `yaml:"spike_duration_s"` // длительность всплеска (по умолчанию jitter.spike_duration_s)
+	} `yaml:"domains"`
+
+	// деградация серверов (gray failures): сервер не отказывает, но на время
+	// теряет часть пропускной способности и/или увеличивает задержку
+	Degradation struct {
+		MTBD      float64  `yaml:"mtbd_s"`       // среднее время между деградациями сервера, сек (0 — нет)
+		Duration  Dist     `yaml:"duration"`     // длительность, сек (по умолчанию экспоненциальная со средним 60)
+		Bandwidth Dist     `yaml:"bandwidth"`    // множитель пропускной способности, (0, 1] (по умолчанию 0.5)
+		Latency   Dist     `yaml:"latency"`      // множитель OWD, >= 1 (по умолчанию 1)
+		Labels    []string `yaml:"labels"`       // метки пулов: серверы метки деградируют одновременно
+		LabelMTBD float64  `yaml:"label_mtbd_s"` // среднее время между деградациями метки, сек
+	} `yaml:"degradation"`
+
+	// автомасштабирование: серверы сверх cluster.servers (до max_servers) создаются
+	// заранее выключенными и включаются контроллером по утилизации из snapshots
+	Autoscale struct {
+		MaxServers int     `yaml:"max_servers"`      // макс. число серверов (0 — автомасштабирования нет)
+		MinServers int     `yaml:"min_servers"`      // мин. число работающих серверов (по умолчанию cluster.servers)
+		Interval   float64 `yaml:"interval_s"`       // период решения, сек (по умолчанию simulation.step_seconds)
+		Target     float64 `yaml:"target"`           // утилизация, выше которой добавляются серверы
+		Low        float64 `yaml:"low"`              // утилизация, ниже которой серверы выводятся
+		Step       int     `yaml:"step"`             // серверов за одно решение
+		Provision  float64 `yaml:"provision_s"`      // задержка запуска сервера, сек
+		Warmup     float64 `yaml:"warmup_s"`         // прогрев после запуска, сек
+		WarmupBW   float64 `yaml:"warmup_bandwidth"` // множитель пропускной способности при прогреве
+		Cooldown   float64 `yaml:"cooldown_s"`       // пауза после масштабирования перед выводом серверов, сек
+		Drain      float64 `yaml:"drain_timeout_s"`  // срок переноса сессий выводимого сервера (0 — ждать их окончания)
+	} `yaml:"autoscale"`
+
+	// плавный перезапуск серверов группами по batch в порядке id: группа выводится
+	// из балансировки (drain), после ухода сессий выключается на downtime_s и возвращается
+	RollingRestart struct {
+		Start        float64 `yaml:"start_s"`         // начало перезапуска, сек
+		Batch        int     `yaml:"batch"`           // серверов в группе (0 — перезапуска нет)
+		DrainTimeout float64 `yaml:"drain_timeout_s"` // срок переноса подключённых сессий, сек (0 — ждать их окончания)
+		Downtime     float64 `yaml:"downtime_s"`      // простой сервера на перезапуск, сек
+		Interval     float64 `yaml:"interval_s"`      // пауза между группами, сек
+	} `yaml:"rolling_restart"`
+
+	// отказы серверов: у каждого сервера независимо чередуются работа и простой
+	Failures struct {
+		MTBF float64 `yaml:"mtbf_s"` // среднее время между отказами, сек (0 — отказов нет)
+		MTTR float64 `yaml:"mttr_s"` // среднее время восстановления, сек
+	} `yaml:"failures"`
+
+	// точки присутствия: у каждой свой пул серверов (пусто — один плоский пул cluster.servers)
+	PoPs []struct {
+		Name    string  `yaml:"name"`
+		Servers int     `yaml:"servers"`    // кол-во серверов в PoP
+		Latency float64 `yaml:"latency_ms"` // задержка до PoP, мс (для balancer.global: latency)
+		Region  string  `yaml:"region"`     // регион PoP из geo.regions
+		Weight  float64 `yaml:"weight"`     // доля «домашних» сессий PoP (для balancer.global: geo), по умолчанию — servers
+	} `yaml:"pops"`
+
+	// география клиентов: OWD клиент–сервер = latency_ms[регион клиента][регион сервера] + OWD сервера
+	Geo struct {
+		Regions []struct {
+			Name   string  `yaml:"name"`
+			Weight float64 `yaml:"weight"` // доля сессий из региона
+		} `yaml:"regions"`
+		Latency [][]float64 `yaml:"latency_ms"` // матрица задержек регион–регион, мс (строки — клиенты, столбцы — серверы)
+
+		regionIdx     map[string]int
+		serverLatency map[serverKey]float64
+	} `yaml:"geo"`
+
+	// топология из файлов (csv или json; пути относительно каталога конфига) вместо случайной генерации
+	Topology struct {
+		ServersFile string `yaml:"servers_file"` // колонки: id, pop, region, mbps, owd_ms, owd_jitter_ms, max_conn; размеры pops берутся из файла
+		LatencyFile string `yaml:"latency_file"` // колонки: client_region, server_id или server_region, owd_ms
+
+		Servers []ServerSpec  `yaml:"-"`
+		Latency []LatencySpec `yaml:"-"`
+	} `yaml:"topology"`
+
 	Jitter struct {
 		Tick       float64 `yaml:"tick_s"`           // шаг обновления OWD
 		SpikeP     float64 `yaml:"spike_prob"`       // вероятность всплеска зедержки
@@ -54,6 +269,29 @@ type Config struct {
 	Balancer struct {
 		Strategy   string `yaml:"strategy"`
 		CHReplicas int    `yaml:"ch_replicas"`
+		HashKey    string `yaml:"hash_key"` // ключ хеширования ch и hierarchical: user, content (ролик каталога)
+		Global     string `yaml:"global"`   // выбор PoP при заданных pops: latency, capacity, geo; strategy — локальная стратегия внутри PoP
+
+		TSThreshold float64 `yaml:"ts_threshold_s"` // успех запроса для thompson: очередь и обслуживание без штрафа редиректа не больше порога, сек
+		TSDiscount  float64 `yaml:"ts_discount"`    // коэффициент забывания апостериорных параметров (1 — без забывания)
+		TSWindow    int     `yaml:"ts_window"`      // скользящее окно по последним исходам сервера (0 — используется ts_discount)
+
+		QLTable       string  `yaml:"ql_table"`        // файл Q-таблицы: сохраняется в режиме train, загружается стратегией ql
+		QLEpisodes    int     `yaml:"ql_episodes"`     // кол-во эпизодов обучения
+		QLAlpha       float64 `yaml:"ql_alpha"`        // скорость обучения
+		QLGamma       float64 `yaml:"ql_gamma"`        // коэффициент дисконтирования
+		QLEpsilon     float64 `yaml:"ql_epsilon"`      // вероятность случайного действия при обучении
+		QLUtilBuckets int     `yaml:"ql_util_buckets"` // кол-во корзин утилизации сервера в состоянии
+
+		PredAffinity  string  `yaml:"pred_affinity"`  // predictive: стратегия в обычном режиме (привязка сессий)
+		PredSpread    string  `yaml:"pred_spread"`    // predictive: стратегия при прогнозируемом всплеске (распределение)
+		PredBin       float64 `yaml:"pred_bin_s"`     // predictive: шаг агрегации поступлений, сек
+		PredAlpha     float64 `yaml:"pred_alpha"`     // predictive: сглаживание уровня (Holt)
+		PredBeta      float64 `yaml:"pred_beta"`      // predictive: сглаживание тренда (0 — простое экспоненциальное сглаживание)
+		PredHorizon   float64 `yaml:"pred_horizon_s"` // predictive: горизонт прогноза, сек
+		PredThreshold float64 `yaml:"pred_threshold"` // predictive: переход в режим распределения, если прогноз > threshold * base_rps
+
+		MPCHorizon float64 `yaml:"mpc_horizon_s"` // mpc: горизонт прогноза нагрузки, сек
 	} `yaml:"balancer"`
 }
 
@@ -70,6 +308,17 @@ func Load(path string) (*Config, error) {
 	if err := dec.Decode(&cfg); err != nil {
 		return nil, fmt.Errorf("error when parsing config: %w", err)
 	}
+	if err := loadTopology(&cfg, filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("error when loading topology: %w", err)
+	}
+	if err := loadTrace(&cfg, filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("error when loading trace: %w", err)
+	}
+	for _, d := range cfg.dists() {
+		if err := d.dist.load(d.name, filepath.Dir(path)); err != nil {
+			return nil, fmt.Errorf("error when loading distribution: %w", err)
+		}
+	}
 
 	fillDefaults(&cfg)
 	if err := validate(&cfg); err != nil {
@@ -78,6 +327,39 @@ func Load(path string) (*Config, error) {
 	return &cfg, nil
 }
 
+// ServerFailures — серверы могут отказывать во время симуляции
+// (запросы в обработке нужно отслеживать)
+func (c *Config) ServerFailures() bool {
+	for _, ev := range c.Scenario {
+		if ev.Action == "fail" {
+			return true
+		}
+	}
+	for _, d := range c.Domains {
+		if d.MTBF > 0 {
+			return true
+		}
+	}
+	return c.Failures.MTBF > 0 || c.RollingRestart.Batch > 0 || c.Autoscale.MaxServers > 0
+}
+
+// TotalServers — все серверы симуляции, включая резерв автомасштабирования
+func (c *Config) TotalServers() int {
+	return max(c.Cluster.Servers, c.Autoscale.MaxServers)
+}
+
+// Strategies — стратегии, между которыми переключается сценарий:
+// balancer.strategy и стратегии действий strategy, без повторов
+func (c *Config) Strategies() []string {
+	strategies := []string{c.Balancer.Strategy}
+	for _, ev := range c.Scenario {
+		if ev.Action == "strategy" && !slices.Contains(strategies, ev.Strategy) {
+			strategies = append(strategies, ev.Strategy)
+		}
+	}
+	return strategies
+}
+
 func fillDefaults(c *Config) {
 	if c.Simulation.TimeSeconds == 0 {
 		c.Simulation.TimeSeconds = 600
@@ -94,6 +376,32 @@ func fillDefaults(c *Config) {
 	if c.Traffic.UsersAmount == 0 {
 		c.Traffic.UsersAmount = 10_000
 	}
+	if c.Traffic.LengthHint == "" {
+		c.Traffic.LengthHint = "none"
+	}
+	if c.Traffic.HintCV == 0 {
+		c.Traffic.HintCV = 0.5
+	}
+	if len(c.Cluster.Pools) > 0 {
+		c.Cluster.Servers = 0
+		counts := make(map[string]int)
+		for _, p := range c.Cluster.Pools {
+			c.Cluster.Servers += p.Count
+			counts[p.PoP] += p.Count
+		}
+		for i := range c.PoPs {
+			c.PoPs[i].Servers = counts[c.PoPs[i].Name]
+		}
+	}
+	if len(c.PoPs) > 0 {
+		c.Cluster.Servers = 0
+		for i := range c.PoPs {
+			c.Cluster.Servers += c.PoPs[i].Servers
+			if c.PoPs[i].Weight == 0 {
+				c.PoPs[i].Weight = float64(c.PoPs[i].Servers)
+			}
+		}
+	}
 	if c.Cluster.Servers == 0 {
 		c.Cluster.Servers = 5
 	}
@@ -118,6 +426,51 @@ func fillDefaults(c *Config) {
 	if c.Cluster.SigmaServer == 0 {
 		c.Cluster.SigmaServer = 0.25
 	}
+	if c.Cluster.BandwidthModel == "" {
+		c.Cluster.BandwidthModel = "fixed"
+	}
+	if c.Failures.MTTR == 0 {
+		c.Failures.MTTR = 60
+	}
+	if c.Cluster.Sessions.Mode == "" {
+		c.Cluster.Sessions.Mode = "none"
+	}
+	if c.Cluster.Queue.Discipline == "" {
+		c.Cluster.Queue.Discipline = "fifo"
+	}
+	if c.Cluster.Cache.Policy == "" {
+		c.Cluster.Cache.Policy = "lru"
+	}
+	if c.Cluster.Cache.OriginMbps == 0 {
+		c.Cluster.Cache.OriginMbps = 1000
+	}
+	if !c.Traffic.Fragments.IsSet() {
+		c.Traffic.Fragments = defaultFragments()
+	}
+	if c.Traffic.Catalog.Items > 0 && !c.Traffic.Catalog.Length.IsSet() {
+		c.Traffic.Catalog.Length = c.Traffic.Fragments
+	}
+	if !c.Cluster.Capacity.IsSet() {
+		c.Cluster.Capacity = Dist{Type: "normal", Mean: c.Cluster.CapMean, CV: c.Cluster.CapCV}
+	}
+	if !c.Cluster.OWD.IsSet() {
+		c.Cluster.OWD = Dist{Type: "gamma", Mean: c.Cluster.OWDMean, CV: c.Cluster.OWDCV}
+	}
+	if !c.Cluster.ServiceNoise.IsSet() {
+		c.Cluster.ServiceNoise = Dist{Type: "lognormal", Sigma: c.Cluster.SigmaServer}
+	}
+	if !c.Cluster.RedirectPenalty.IsSet() {
+		c.Cluster.RedirectPenalty = Dist{Type: "constant", Value: 100}
+	}
+	if !c.Degradation.Duration.IsSet() {
+		c.Degradation.Duration = Dist{Type: "gamma", Mean: 60, CV: 1}
+	}
+	if !c.Degradation.Bandwidth.IsSet() {
+		c.Degradation.Bandwidth = Dist{Type: "constant", Value: 0.5}
+	}
+	if !c.Degradation.Latency.IsSet() {
+		c.Degradation.Latency = Dist{Type: "constant", Value: 1}
+	}
 	if c.Cluster.MaxRetriesPerSegment == 0 {
 		c.Cluster.MaxRetriesPerSegment = 2
 	}
@@ -136,17 +489,715 @@ func fillDefaults(c *Config) {
 	if c.Jitter.SpikeDur == 0 {
 		c.Jitter.SpikeDur = 5.0
 	}
+	if c.Autoscale.MaxServers > 0 {
+		a := &c.Autoscale
+		if a.MinServers == 0 {
+			a.MinServers = c.Cluster.Servers
+		}
+		if a.Interval == 0 {
+			a.Interval = c.Simulation.StepSeconds
+		}
+		if a.Target == 0 {
+			a.Target = 0.7
+		}
+		if a.Low == 0 {
+			a.Low = 0.3
+		}
+		if a.Step == 0 {
+			a.Step = 1
+		}
+		if a.Provision == 0 {
+			a.Provision = 60
+		}
+		if a.Warmup == 0 {
+			a.Warmup = 30
+		}
+		if a.WarmupBW == 0 {
+			a.WarmupBW = 0.5
+		}
+		if a.Cooldown == 0 {
+			a.Cooldown = 120
+		}
+	}
+	if c.Traffic.Arrivals.Process == "" {
+		c.Traffic.Arrivals.Process = "poisson"
+	}
+	if c.Traffic.Arrivals.Process == "pareto" && c.Traffic.Arrivals.Shape == 0 {
+		c.Traffic.Arrivals.Shape = 1.5
+	}
+	if c.Traffic.Trace.Speed == 0 {
+		c.Traffic.Trace.Speed = 1
+	}
+	if c.Traffic.Profile.Diurnal.Period == 0 {
+		c.Traffic.Profile.Diurnal.Period = 86400
+	}
+	for i := range c.Spikes {
+		if c.Spikes[i].Mode == "" {
+			c.Spikes[i].Mode = "mul"
+		}
+	}
+	for i := range c.Domains {
+		d := &c.Domains[i]
+		if d.MTTR == 0 {
+			d.MTTR = c.Failures.MTTR
+		}
+		if d.SpikeExtra == 0 {
+			d.SpikeExtra = c.Jitter.SpikeExtra
+		}
+		if d.SpikeDur == 0 {
+			d.SpikeDur = c.Jitter.SpikeDur
+		}
+	}
 	if c.Balancer.Strategy == "" {
 		c.Balancer.Strategy = "ch"
 	}
 	if c.Balancer.CHReplicas == 0 {
 		c.Balancer.CHReplicas = 100
 	}
+	if c.Balancer.HashKey == "" {
+		c.Balancer.HashKey = "user"
+	}
+	if c.Balancer.Global == "" {
+		c.Balancer.Global = "latency"
+	}
+	if c.Balancer.TSThreshold == 0 {
+		c.Balancer.TSThreshold = 1.0
+	}
+	if c.Balancer.TSDiscount == 0 {
+		c.Balancer.TSDiscount = 0.999
+	}
+	if c.Balancer.QLTable == "" {
+		c.Balancer.QLTable = "./ql_table.json"
+	}
+	if c.Balancer.QLEpisodes == 0 {
+		c.Balancer.QLEpisodes = 10
+	}
+	if c.Balancer.QLAlpha == 0 {
+		c.Balancer.QLAlpha = 0.1
+	}
+	if c.Balancer.QLGamma == 0 {
+		c.Balancer.QLGamma = 0.9
+	}
+	if c.Balancer.QLEpsilon == 0 {
+		c.Balancer.QLEpsilon = 0.1
+	}
+	if c.Balancer.QLUtilBuckets == 0 {
+		c.Balancer.QLUtilBuckets = 5
+	}
+	if c.Balancer.PredAffinity == "" {
+		c.Balancer.PredAffinity = "ch"
+	}
+	if c.Balancer.PredSpread == "" {
+		c.Balancer.PredSpread = "p2c"
+	}
+	if c.Balancer.PredBin == 0 {
+		c.Balancer.PredBin = 1
+	}
+	if c.Balancer.PredAlpha == 0 {
+		c.Balancer.PredAlpha = 0.5
+	}
+	if c.Balancer.PredHorizon == 0 {
+		c.Balancer.PredHorizon = 5
+	}
+	if c.Balancer.PredThreshold == 0 {
+		c.Balancer.PredThreshold = 1.5
+	}
+	if c.Balancer.MPCHorizon == 0 {
+		c.Balancer.MPCHorizon = 60
+	}
+
+	for i := range c.Cluster.Pools {
+		p := &c.Cluster.Pools[i]
+		if p.CapMean == 0 {
+			p.CapMean = c.Cluster.CapMean
+		}
+		if p.CapCV == 0 {
+			p.CapCV = c.Cluster.CapCV
+		}
+		if p.OWDMean == 0 {
+			p.OWDMean = c.Cluster.OWDMean
+		}
+		if p.OWDCV == 0 {
+			p.OWDCV = c.Cluster.OWDCV
+		}
+		if p.Bitrate == 0 {
+			p.Bitrate = c.Cluster.Bitrate
+		}
+		if !p.Capacity.IsSet() {
+			p.Capacity = Dist{Type: "normal", Mean: p.CapMean, CV: p.CapCV}
+		}
+		if !p.OWD.IsSet() {
+			p.OWD = Dist{Type: "gamma", Mean: p.OWDMean, CV: p.OWDCV}
+		}
+	}
+
+	c.Geo.regionIdx = make(map[string]int, len(c.Geo.Regions))
+	for i, r := range c.Geo.Regions {
+		c.Geo.regionIdx[r.Name] = i
+	}
+	fillTraceRegions(c)
 
 	c.Cluster.SegmentSizeBytes = c.Cluster.Bitrate * 1_000_000 / 8 * c.Cluster.SegmentDuration
 }
 
 func validate(cfg *Config) error {
 	// TODO: validate
+	switch cfg.Traffic.LengthHint {
+	case "none", "exact", "noisy":
+	default:
+		return fmt.Errorf("traffic.length_hint must be one of none, exact, noisy, got %q", cfg.Traffic.LengthHint)
+	}
+	switch cfg.Cluster.BandwidthModel {
+	case "fixed", "ps":
+	default:
+		return fmt.Errorf("cluster.bandwidth_model must be one of fixed, ps, got %q", cfg.Cluster.BandwidthModel)
+	}
+	switch cfg.Cluster.Queue.Discipline {
+	case "fifo", "lifo", "priority":
+	default:
+		return fmt.Errorf("cluster.queue.discipline must be one of fifo, lifo, priority, got %q", cfg.Cluster.Queue.Discipline)
+	}
+	if cfg.Failures.MTBF < 0 || cfg.Failures.MTTR <= 0 {
+		return fmt.Errorf("failures: mtbf_s must be >= 0 and mttr_s > 0, got %v, %v", cfg.Failures.MTBF, cfg.Failures.MTTR)
+	}
+	if err := validateArrivals(cfg); err != nil {
+		return err
+	}
+	if cat := cfg.Traffic.Catalog; cat.Items < 0 || cat.Zipf < 0 {
+		return fmt.Errorf("traffic.catalog: items and zipf must be >= 0, got %v, %v", cat.Items, cat.Zipf)
+	}
+	if u := cfg.Traffic.Users; u.Zipf < 0 || u.ReturnP < 0 || u.ReturnP > 1 || u.MaxConcurrent < 0 {
+		return fmt.Errorf("traffic.users: zipf must be >= 0, return_p in [0, 1] and max_concurrent >= 0, got %v, %v, %v",
+			u.Zipf, u.ReturnP, u.MaxConcurrent)
+	}
+	if err := validateProfile(cfg); err != nil {
+		return err
+	}
+	if err := validateAutoscale(cfg); err != nil {
+		return err
+	}
+	if err := validateDomains(cfg); err != nil {
+		return err
+	}
+	if err := validateScenario(cfg); err != nil {
+		return err
+	}
+	rr := cfg.RollingRestart
+	if rr.Batch < 0 || rr.Batch > cfg.Cluster.Servers {
+		return fmt.Errorf("rolling_restart.batch must be in 0..%d, got %d", cfg.Cluster.Servers, rr.Batch)
+	}
+	if rr.Start < 0 || rr.DrainTimeout < 0 || rr.Downtime < 0 || rr.Interval < 0 {
+		return fmt.Errorf("rolling_restart: start_s, drain_timeout_s, downtime_s and interval_s must be >= 0")
+	}
+	if err := validateSessions(cfg); err != nil {
+		return err
+	}
+	if err := validateCache(cfg); err != nil {
+		return err
+	}
+	if cfg.Cluster.Queue.Size < 0 || cfg.Cluster.Queue.Timeout < 0 {
+		return fmt.Errorf("cluster.queue: size and timeout_s must be >= 0, got %d, %v", cfg.Cluster.Queue.Size, cfg.Cluster.Queue.Timeout)
+	}
+	if cfg.Traffic.HintCV < 0 {
+		return fmt.Errorf("traffic.hint_cv must be >= 0, got %v", cfg.Traffic.HintCV)
+	}
+	for _, d := range cfg.dists() {
+		if err := d.dist.validate(d.name); err != nil {
+			return err
+		}
+	}
+	for _, d := range []namedDist{
+		{"cluster.owd", &cfg.Cluster.OWD},
+		{"cluster.service_noise", &cfg.Cluster.ServiceNoise},
+		{"cluster.redirect_penalty", &cfg.Cluster.RedirectPenalty},
+		{"degradation.duration", &cfg.Degradation.Duration},
+		{"degradation.bandwidth", &cfg.Degradation.Bandwidth},
+		{"degradation.latency", &cfg.Degradation.Latency},
+	} {
+		if err := d.dist.nonNegative(d.name); err != nil {
+			return err
+		}
+	}
+	if err := validatePools(cfg); err != nil {
+		return err
+	}
+	if err := validateDegradation(cfg); err != nil {
+		return err
+	}
+	names := make(map[string]bool, len(cfg.PoPs))
+	for i, p := range cfg.PoPs {
+		if p.Name == "" {
+			return fmt.Errorf("pops[%d]: name is required", i)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("pops[%d]: duplicate name %q", i, p.Name)
+		}
+		names[p.Name] = true
+		if p.Servers <= 0 {
+			return fmt.Errorf("pops[%d] (%s): servers must be > 0, got %d", i, p.Name, p.Servers)
+		}
+		if p.Weight < 0 {
+			return fmt.Errorf("pops[%d] (%s): weight must be >= 0, got %v", i, p.Name, p.Weight)
+		}
+	}
+	if err := validateTopology(cfg); err != nil {
+		return err
+	}
+	if err := validateGeo(cfg); err != nil {
+		return err
+	}
+	if err := validateTrace(cfg); err != nil {
+		return err
+	}
+	switch cfg.Balancer.HashKey {
+	case "user", "content":
+	default:
+		return fmt.Errorf("balancer.hash_key must be one of user, content, got %q", cfg.Balancer.HashKey)
+	}
+	switch cfg.Balancer.Global {
+	case "latency", "capacity", "geo":
+	default:
+		return fmt.Errorf("balancer.global must be one of latency, capacity, geo, got %q", cfg.Balancer.Global)
+	}
+	if cfg.Balancer.TSDiscount <= 0 || cfg.Balancer.TSDiscount > 1 {
+		return fmt.Errorf("balancer.ts_discount must be in (0, 1], got %v", cfg.Balancer.TSDiscount)
+	}
+	if cfg.Balancer.TSWindow < 0 {
+		return fmt.Errorf("balancer.ts_window must be >= 0, got %d", cfg.Balancer.TSWindow)
+	}
+	if cfg.Balancer.QLEpisodes < 0 {
+		return fmt.Errorf("balancer.ql_episodes must be >= 0, got %d", cfg.Balancer.QLEpisodes)
+	}
+	if cfg.Balancer.QLUtilBuckets < 1 {
+		return fmt.Errorf("balancer.ql_util_buckets must be >= 1, got %d", cfg.Balancer.QLUtilBuckets)
+	}
+	if cfg.Balancer.QLEpsilon < 0 || cfg.Balancer.QLEpsilon > 1 {
+		return fmt.Errorf("balancer.ql_epsilon must be in [0, 1], got %v", cfg.Balancer.QLEpsilon)
+	}
+	if cfg.Balancer.PredAlpha <= 0 || cfg.Balancer.PredAlpha > 1 {
+		return fmt.Errorf("balancer.pred_alpha must be in (0, 1], got %v", cfg.Balancer.PredAlpha)
+	}
+	if cfg.Balancer.PredBeta < 0 || cfg.Balancer.PredBeta > 1 {
+		return fmt.Errorf("balancer.pred_beta must be in [0, 1], got %v", cfg.Balancer.PredBeta)
+	}
+	if cfg.Balancer.PredBin <= 0 {
+		return fmt.Errorf("balancer.pred_bin_s must be > 0, got %v", cfg.Balancer.PredBin)
+	}
+	if cfg.Balancer.PredHorizon <= 0 {
+		return fmt.Errorf("balancer.pred_horizon_s must be > 0, got %v", cfg.Balancer.PredHorizon)
+	}
+	// predictive внутри своих стратегий строился бы рекурсивно без конца
+	for _, s := range []string{cfg.Balancer.PredAffinity, cfg.Balancer.PredSpread} {
+		for _, part := range strings.Split(s, "+") {
+			if strings.TrimSpace(part) == "predictive" {
+				return fmt.Errorf("balancer.pred_affinity and pred_spread must not contain predictive, got %q", s)
+			}
+		}
+	}
+	if cfg.Balancer.MPCHorizon <= 0 {
+		return fmt.Errorf("balancer.mpc_horizon_s must be > 0, got %v", cfg.Balancer.MPCHorizon)
+	}
 	return nil
 }
+
+func validateArrivals(cfg *Config) error {
+	a := &cfg.Traffic.Arrivals
+	switch a.Process {
+	case "poisson":
+	case "mmpp":
+		if len(a.MMPP) < 2 {
+			return fmt.Errorf("traffic.arrivals: mmpp requires at least 2 states, got %d", len(a.MMPP))
+		}
+		active := false
+		for i, s := range a.MMPP {
+			if s.Factor < 0 || s.Duration <= 0 {
+				return fmt.Errorf("traffic.arrivals.mmpp[%d]: factor must be >= 0 and mean_duration_s > 0, got %v, %v", i, s.Factor, s.Duration)
+			}
+			active = active || s.Factor > 0
+		}
+		if !active {
+			return fmt.Errorf("traffic.arrivals: mmpp requires at least one state with factor > 0")
+		}
+	case "hawkes":
+		if a.Branching < 0 || a.Branching >= 1 || a.Decay <= 0 {
+			return fmt.Errorf("traffic.arrivals: hawkes requires branching in [0, 1) and decay_s > 0, got %v, %v", a.Branching, a.Decay)
+		}
+	case "batch":
+		if !a.BatchSize.IsSet() {
+			return fmt.Errorf("traffic.arrivals: batch requires batch_size")
+		}
+		if err := a.BatchSize.nonNegative("traffic.arrivals.batch_size"); err != nil {
+			return err
+		}
+		if a.BatchSize.Expected() < 1 {
+			return fmt.Errorf("traffic.arrivals.batch_size: mean must be >= 1, got %v", a.BatchSize.Expected())
+		}
+	case "pareto":
+		if a.Shape <= 1 {
+			return fmt.Errorf("traffic.arrivals: pareto requires shape > 1, got %v", a.Shape)
+		}
+	default:
+		return fmt.Errorf("traffic.arrivals.process must be one of poisson, mmpp, hawkes, batch, pareto, got %q", a.Process)
+	}
+	return nil
+}
+
+func validateProfile(cfg *Config) error {
+	if cfg.Traffic.BaseRPS <= 0 {
+		return fmt.Errorf("traffic.base_rps must be > 0, got %v", cfg.Traffic.BaseRPS)
+	}
+	p := &cfg.Traffic.Profile
+	if p.Diurnal.Amplitude < 0 || p.Diurnal.Amplitude >= 1 || p.Diurnal.Period <= 0 {
+		return fmt.Errorf("traffic.profile.diurnal: amplitude must be in [0, 1) and period_s > 0, got %v, %v",
+			p.Diurnal.Amplitude, p.Diurnal.Period)
+	}
+	for i, r := range p.Ramps {
+		if r.Factor < 0 { // 0 — поступления приостанавливаются
+			return fmt.Errorf("traffic.profile.ramps[%d]: factor must be >= 0, got %v", i, r.Factor)
+		}
+		if i > 0 && r.At <= p.Ramps[i-1].At {
+			return fmt.Errorf("traffic.profile.ramps[%d]: at must be increasing, got %v after %v", i, r.At, p.Ramps[i-1].At)
+		}
+	}
+	for i, sp := range cfg.Spikes {
+		if sp.At < 0 || sp.Duration < 0 || sp.Decay < 0 {
+			return fmt.Errorf("spikes[%d]: at, duration and decay_s must be >= 0", i)
+		}
+		switch sp.Mode {
+		case "mul":
+			if sp.Factor <= 0 {
+				return fmt.Errorf("spikes[%d]: mul requires factor > 0, got %v", i, sp.Factor)
+			}
+		case "add":
+			if sp.Factor < 0 {
+				return fmt.Errorf("spikes[%d]: add requires factor >= 0, got %v", i, sp.Factor)
+			}
+		default:
+			return fmt.Errorf("spikes[%d]: mode must be one of mul, add, got %q", i, sp.Mode)
+		}
+	}
+	return nil
+}
+
+func validateAutoscale(cfg *Config) error {
+	a := &cfg.Autoscale
+	if a.MaxServers == 0 {
+		return nil
+	}
+	if len(cfg.PoPs) > 0 || len(cfg.Cluster.Pools) > 0 || cfg.Topology.ServersFile != "" {
+		return fmt.Errorf("autoscale requires a flat cluster without pops, cluster.pools and topology.servers_file")
+	}
+	if a.MaxServers < cfg.Cluster.Servers {
+		return fmt.Errorf("autoscale.max_servers must be >= cluster.servers (%d), got %d", cfg.Cluster.Servers, a.MaxServers)
+	}
+	if a.MinServers < 1 || a.MinServers > cfg.Cluster.Servers {
+		return fmt.Errorf("autoscale.min_servers must be in 1..%d, got %d", cfg.Cluster.Servers, a.MinServers)
+	}
+	if a.Target <= 0 || a.Target > 1 || a.Low < 0 || a.Low >= a.Target {
+		return fmt.Errorf("autoscale: must be 0 <= low < target <= 1, got %v, %v", a.Low, a.Target)
+	}
+	if a.Interval <= 0 || a.Step < 1 {
+		return fmt.Errorf("autoscale: interval_s must be > 0 and step >= 1, got %v, %d", a.Interval, a.Step)
+	}
+	if a.Provision < 0 || a.Warmup < 0 || a.Cooldown < 0 || a.Drain < 0 {
+		return fmt.Errorf("autoscale: provision_s, warmup_s, cooldown_s and drain_timeout_s must be >= 0")
+	}
+	if a.WarmupBW <= 0 || a.WarmupBW > 1 {
+		return fmt.Errorf("autoscale.warmup_bandwidth must be in (0, 1], got %v", a.WarmupBW)
+	}
+	return nil
+}
+
+func validateDomains(cfg *Config) error {
+	names := make(map[string]bool, len(cfg.Domains))
+	pops := make(map[string]bool, len(cfg.PoPs))
+	for _, p := range cfg.PoPs {
+		pops[p.Name] = true
+	}
+	for i, d := range cfg.Domains {
+		if d.Name == "" || strings.ContainsAny(d.Name, ";,") {
+			return fmt.Errorf("domains[%d]: name is required and must not contain ';' or ','", i)
+		}
+		if names[d.Name] {
+			return fmt.Errorf("domains[%d]: duplicate name %q", i, d.Name)
+		}
+		names[d.Name] = true
+		if len(d.Servers) == 0 && len(d.Labels) == 0 && d.PoP == "" {
+			return fmt.Errorf("domains[%d]: one of servers, labels or pop is required", i)
+		}
+		for _, id := range d.Servers {
+			if id < 1 || id > cfg.TotalServers() {
+				return fmt.Errorf("domains[%d]: server id must be in 1..%d, got %d", i, cfg.TotalServers(), id)
+			}
+		}
+		for _, label := range d.Labels {
+			found := false
+			for _, p := range cfg.Cluster.Pools {
+				found = found || slices.Contains(p.Labels, label)
+			}
+			if !found {
+				return fmt.Errorf("domains[%d]: label %q is not used by any of cluster.pools", i, label)
+			}
+		}
+		if d.PoP != "" && !pops[d.PoP] {
+			return fmt.Errorf("domains[%d]: unknown pop %q", i, d.PoP)
+		}
+		if d.MTBF < 0 || d.MTTR <= 0 {
+			return fmt.Errorf("domains[%d]: mtbf_s must be >= 0 and mttr_s > 0, got %v, %v", i, d.MTBF, d.MTTR)
+		}
+		if d.SpikeP < 0 || d.SpikeP > 1 || d.SpikeExtra < 0 || d.SpikeDur < 0 {
+			return fmt.Errorf("domains[%d]: spike_prob must be in [0, 1], spike_extra and spike_duration_s >= 0", i)
+		}
+	}
+	return nil
+}
+
+func validateScenario(cfg *Config) error {
+	for i, ev := range cfg.Scenario {
+		if ev.At < 0 || ev.Duration < 0 {
+			return fmt.Errorf("scenario[%d]: at and duration must be >= 0, got %v, %v", i, ev.At, ev.Duration)
+		}
+		switch ev.Action {
+		case "fail", "owd":
+		case "drain":
+			if ev.Deadline < 0 {
+				return fmt.Errorf("scenario[%d]: deadline_s must be >= 0, got %v", i, ev.Deadline)
+			}
+		case "recover":
+			if ev.Duration > 0 {
+				return fmt.Errorf("scenario[%d]: recover cannot have duration", i)
+			}
+		case "capacity", "traffic":
+			if ev.Factor <= 0 {
+				return fmt.Errorf("scenario[%d]: %s requires factor > 0, got %v", i, ev.Action, ev.Factor)
+			}
+		case "strategy":
+			if ev.Strategy == "" {
+				return fmt.Errorf("scenario[%d]: strategy requires strategy", i)
+			}
+		default:
+			return fmt.Errorf("scenario[%d]: action must be one of fail, recover, drain, capacity, owd, traffic, strategy, got %q", i, ev.Action)
+		}
+		if ev.Domain != "" {
+			if len(ev.Servers) > 0 {
+				return fmt.Errorf("scenario[%d]: servers and domain are mutually exclusive", i)
+			}
+			found := false
+			for _, d := range cfg.Domains {
+				found = found || d.Name == ev.Domain
+			}
+			if !found {
+				return fmt.Errorf("scenario[%d]: unknown domain %q", i, ev.Domain)
+			}
+		}
+		if ev.Deadline != 0 && ev.Action != "drain" {
+			return fmt.Errorf("scenario[%d]: deadline_s is only allowed for drain", i)
+		}
+		if (len(ev.Servers) > 0 || ev.Domain != "") && (ev.Action == "traffic" || ev.Action == "strategy") {
+			return fmt.Errorf("scenario[%d]: servers and domain are not allowed for %s", i, ev.Action)
+		}
+		for _, id := range ev.Servers {
+			if id < 1 || id > cfg.TotalServers() {
+				return fmt.Errorf("scenario[%d]: server id must be in 1..%d, got %d", i, cfg.TotalServers(), id)
+			}
+		}
+	}
+	return nil
+}
+
+func validateSessions(cfg *Config) error {
+	ss := cfg.Cluster.Sessions
+	switch ss.Mode {
+	case "none", "slot":
+	default:
+		return fmt.Errorf("cluster.sessions.mode must be one of none, slot, got %q", ss.Mode)
+	}
+	if ss.Reserve < 0 {
+		return fmt.Errorf("cluster.sessions.reserve_mbps must be >= 0, got %v", ss.Reserve)
+	}
+	if ss.Reserve == 0 {
+		return nil
+	}
+	// при slot сессий не больше max_conn = mbps / bitrate, и резерв меньше битрейта
+	// оставляет передачам часть полосы
+	if ss.Mode != "slot" {
+		return fmt.Errorf("cluster.sessions.reserve_mbps requires mode slot")
+	}
+	bitrate := cfg.Cluster.Bitrate
+	for _, p := range cfg.Cluster.Pools {
+		bitrate = min(bitrate, p.Bitrate)
+	}
+	if ss.Reserve >= bitrate {
+		return fmt.Errorf("cluster.sessions.reserve_mbps must be < bitrate (%v), got %v", bitrate, ss.Reserve)
+	}
+	return nil
+}
+
+func validateCache(cfg *Config) error {
+	c := cfg.Cluster.Cache
+	switch c.Policy {
+	case "lru", "lfu", "s3fifo":
+	default:
+		return fmt.Errorf("cluster.cache.policy must be one of lru, lfu, s3fifo, got %q", c.Policy)
+	}
+	if c.Bytes < 0 || c.OriginRTT < 0 || c.OriginMbps <= 0 {
+		return fmt.Errorf("cluster.cache: capacity_bytes and origin_rtt_ms must be >= 0 and origin_mbps > 0, got %v, %v, %v",
+			c.Bytes, c.OriginRTT, c.OriginMbps)
+	}
+	return nil
+}
+
+func validateDegradation(cfg *Config) error {
+	d := &cfg.Degradation
+	if d.MTBD < 0 || d.LabelMTBD < 0 {
+		return fmt.Errorf("degradation: mtbd_s and label_mtbd_s must be >= 0, got %v, %v", d.MTBD, d.LabelMTBD)
+	}
+	if lo, hi := d.Bandwidth.support(); lo <= 0 || hi > 1 {
+		return fmt.Errorf("degradation.bandwidth must be in (0, 1], %s can produce values in [%v, %v]", d.Bandwidth.Type, lo, hi)
+	}
+	if lo, _ := d.Latency.support(); lo < 1 {
+		return fmt.Errorf("degradation.latency must be >= 1, %s can produce values from %v", d.Latency.Type, lo)
+	}
+	if len(d.Labels) > 0 && d.LabelMTBD == 0 {
+		return fmt.Errorf("degradation.labels requires label_mtbd_s > 0")
+	}
+	for _, label := range d.Labels {
+		found := false
+		for _, p := range cfg.Cluster.Pools {
+			found = found || slices.Contains(p.Labels, label)
+		}
+		if !found {
+			return fmt.Errorf("degradation.labels: label %q is not used by any of cluster.pools", label)
+		}
+	}
+	return nil
+}
+
+func validatePools(cfg *Config) error {
+	pools := cfg.Cluster.Pools
+	if len(pools) == 0 {
+		return nil
+	}
+	if cfg.Topology.ServersFile != "" {
+		return fmt.Errorf("cluster.pools and topology.servers_file are mutually exclusive")
+	}
+	pops := make(map[string]bool, len(cfg.PoPs))
+	for _, p := range cfg.PoPs {
+		pops[p.Name] = true
+	}
+	names := make(map[string]bool, len(pools))
+	for i, p := range pools {
+		if p.Name == "" {
+			return fmt.Errorf("cluster.pools[%d]: name is required", i)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("cluster.pools[%d]: duplicate name %q", i, p.Name)
+		}
+		names[p.Name] = true
+		if p.Count <= 0 {
+			return fmt.Errorf("cluster.pools[%d] (%s): count must be > 0, got %d", i, p.Name, p.Count)
+		}
+		if err := p.OWD.nonNegative(fmt.Sprintf("cluster.pools[%d].owd", i)); err != nil {
+			return err
+		}
+		if p.Bitrate <= 0 {
+			return fmt.Errorf("cluster.pools[%d] (%s): bitrate must be > 0, got %v", i, p.Name, p.Bitrate)
+		}
+		if p.MinMbps < 0 || (p.MaxMbps != 0 && p.MaxMbps < max(p.MinMbps, p.Bitrate)) {
+			return fmt.Errorf("cluster.pools[%d] (%s): need 0 <= min_mbps and max_mbps >= max(min_mbps, bitrate), got %v, %v",
+				i, p.Name, p.MinMbps, p.MaxMbps)
+		}
+		if len(cfg.PoPs) > 0 && !pops[p.PoP] {
+			return fmt.Errorf("cluster.pools[%d] (%s): unknown pop %q", i, p.Name, p.PoP)
+		}
+		if len(cfg.PoPs) == 0 && p.PoP != "" {
+			return fmt.Errorf("cluster.pools[%d] (%s): pop %q set, but pops are not configured", i, p.Name, p.PoP)
+		}
+		for _, l := range p.Labels {
+			if l == "" || strings.ContainsAny(l, ";,") {
+				return fmt.Errorf("cluster.pools[%d] (%s): label %q must be non-empty and contain no ';' or ','", i, p.Name, l)
+			}
+		}
+	}
+	return nil
+}
+
+func validateGeo(cfg *Config) error {
+	regions := cfg.Geo.Regions
+	if len(regions) == 0 {
+		for i, p := range cfg.PoPs {
+			if p.Region != "" {
+				return fmt.Errorf("pops[%d] (%s): region %q set, but geo.regions is empty", i, p.Name, p.Region)
+			}
+		}
+		return nil
+	}
+
+	total := 0.0
+	for i, r := range regions {
+		if r.Name == "" {
+			return fmt.Errorf("geo.regions[%d]: name is required", i)
+		}
+		if cfg.Geo.regionIdx[r.Name] != i {
+			return fmt.Errorf("geo.regions[%d]: duplicate name %q", i, r.Name)
+		}
+		if r.Weight < 0 {
+			return fmt.Errorf("geo.regions[%d] (%s): weight must be >= 0, got %v", i, r.Name, r.Weight)
+		}
+		total += r.Weight
+	}
+	if total <= 0 {
+		return fmt.Errorf("geo.regions: sum of weights must be > 0")
+	}
+	if len(cfg.Geo.Latency) != len(regions) {
+		return fmt.Errorf("geo.latency_ms must have %d rows, got %d", len(regions), len(cfg.Geo.Latency))
+	}
+	for i, row := range cfg.Geo.Latency {
+		if len(row) != len(regions) {
+			return fmt.Errorf("geo.latency_ms[%d] must have %d columns, got %d", i, len(regions), len(row))
+		}
+		for j, v := range row {
+			if v < 0 {
+				return fmt.Errorf("geo.latency_ms[%d][%d] must be >= 0, got %v", i, j, v)
+			}
+		}
+	}
+	for i, p := range cfg.PoPs {
+		if _, ok := cfg.Geo.regionIdx[p.Region]; !ok {
+			return fmt.Errorf("pops[%d] (%s): unknown region %q", i, p.Name, p.Region)
+		}
+	}
+	return nil
+}
+
+// ChooseRegion — регион клиента пропорционально весам geo.regions (пусто, если география не задана)
+func (c *Config) ChooseRegion(rng *common.RNG) string {
+	regions := c.Geo.Regions
+	if len(regions) == 0 {
+		return ""
+	}
+	total := 0.0
+	for _, r := range regions {
+		total += r.Weight
+	}
+	x := rng.Float64() * total
+	acc := 0.0
+	for _, r := range regions {
+		acc += r.Weight
+		if x < acc {
+			return r.Name
+		}
+	}
+	return regions[len(regions)-1].Name
+}
+
+// RegionLatency — задержка между регионом клиента и регионом сервера, мс
+// (0, если география не задана или регион неизвестен)
+func (c *Config) RegionLatency(client, server string) float64 {
+	i, ok1 := c.Geo.regionIdx[client]
+	j, ok2 := c.Geo.regionIdx[server]
+	if !ok1 || !ok2 {
+		return 0
+	}
+	return c.Geo.Latency[i][j]
+}
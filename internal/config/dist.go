@@ -0,0 +1,352 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// Dist — распределение случайной величины из конфига:
+//
+//	constant:  value
+//	uniform:   min, max (int: true — целое равномерно из [min, max])
+//	normal:    mean, cv или std
+//	gamma:     mean, cv
+//	lognormal: mu, sigma (параметры нормального распределения логарифма)
+//	pareto:    scale (x_m), shape (alpha)
+//	weibull:   scale (lambda), shape (k)
+//	mixture:   components — распределения с весами weight
+//	empirical: file — выборка, значения берутся равновероятно (по одному числу в строке)
+//
+// int: true округляет значения до целых
+type Dist struct {
+	Type       string  `yaml:"type"`
+	Value      float64 `yaml:"value"`
+	Min        float64 `yaml:"min"`
+	Max        float64 `yaml:"max"`
+	Mean       float64 `yaml:"mean"`
+	CV         float64 `yaml:"cv"`
+	Std        float64 `yaml:"std"`
+	Mu         float64 `yaml:"mu"`
+	Sigma      float64 `yaml:"sigma"`
+	Scale      float64 `yaml:"scale"`
+	Shape      float64 `yaml:"shape"`
+	Weight     float64 `yaml:"weight"` // вес компоненты смеси
+	Components []Dist  `yaml:"components"`
+	File       string  `yaml:"file"`
+	Int        bool    `yaml:"int"`
+
+	values []float64 // выборка из file
+}
+
+func (d *Dist) IsSet() bool {
+	return d.Type != ""
+}
+
+func (d *Dist) Sample(rng *common.RNG) float64 {
+	var x float64
+	switch d.Type {
+	case "constant":
+		x = d.Value
+	case "uniform":
+		if d.Int {
+			return d.Min + float64(rng.Intn(int(d.Max-d.Min)+1))
+		}
+		x = distuv.Uniform{Min: d.Min, Max: d.Max, Src: rng}.Rand()
+	case "normal":
+		x = distuv.Normal{Mu: d.Mean, Sigma: d.std(), Src: rng}.Rand()
+	case "gamma":
+		k := 1.0 / (d.CV * d.CV)
+		x = distuv.Gamma{Alpha: k, Beta: 1.0 / (d.Mean / k), Src: rng}.Rand()
+	case "lognormal":
+		x = distuv.LogNormal{Mu: d.Mu, Sigma: d.Sigma, Src: rng}.Rand()
+	case "pareto":
+		x = distuv.Pareto{Xm: d.Scale, Alpha: d.Shape, Src: rng}.Rand()
+	case "weibull":
+		x = distuv.Weibull{Lambda: d.Scale, K: d.Shape, Src: rng}.Rand()
+	case "mixture":
+		r := rng.Float64() * d.totalWeight()
+		acc := 0.0
+		for i := range d.Components {
+			acc += d.Components[i].Weight
+			if r <= acc {
+				return d.Components[i].Sample(rng)
+			}
+		}
+		return d.Components[len(d.Components)-1].Sample(rng)
+	case "empirical":
+		x = d.values[rng.Intn(len(d.values))]
+	}
+	if d.Int {
+		x = math.Round(x)
+	}
+	return x
+}
+
+// Expected — матожидание (без учёта округления int, кроме uniform)
+func (d *Dist) Expected() float64 {
+	switch d.Type {
+	case "constant":
+		return d.Value
+	case "uniform":
+		return (d.Min + d.Max) / 2
+	case "normal", "gamma":
+		return d.Mean
+	case "lognormal":
+		return math.Exp(d.Mu + d.Sigma*d.Sigma/2)
+	case "pareto":
+		if d.Shape <= 1 {
+			return math.Inf(1)
+		}
+		return d.Shape * d.Scale / (d.Shape - 1)
+	case "weibull":
+		return d.Scale * math.Gamma(1+1/d.Shape)
+	case "mixture":
+		mean := 0.0
+		for i := range d.Components {
+			mean += d.Components[i].Weight * d.Components[i].Expected()
+		}
+		return mean / d.totalWeight()
+	case "empirical":
+		mean := 0.0
+		for _, v := range d.values {
+			mean += v
+		}
+		return mean / float64(len(d.values))
+	}
+	return 0
+}
+
+func (d *Dist) std() float64 {
+	if d.Std > 0 {
+		return d.Std
+	}
+	return d.Mean * d.CV
+}
+
+func (d *Dist) totalWeight() float64 {
+	total := 0.0
+	for _, c := range d.Components {
+		total += c.Weight
+	}
+	return total
+}
+
+// load читает выборки empirical (пути — относительно каталога конфига)
+func (d *Dist) load(name, base string) error {
+	for i := range d.Components {
+		if err := d.Components[i].load(fmt.Sprintf("%s.components[%d]", name, i), base); err != nil {
+			return err
+		}
+	}
+	if d.File == "" {
+		return nil
+	}
+	d.File = resolve(base, d.File)
+	values, err := readSample(d.File)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	d.values = values
+	return nil
+}
+
+// readSample читает по одному числу в строке (первая колонка csv);
+// пустые строки, комментарии # и нечисловой заголовок пропускаются
+func readSample(path string) ([]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var values []float64
+	sc := bufio.NewScanner(f)
+	for row := 1; sc.Scan(); row++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		field, _, _ := strings.Cut(line, ",")
+		v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			if row == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("%s:%d: invalid number %q", path, row, field)
+		}
+		values = append(values, v)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%s: no values", path)
+	}
+	return values, nil
+}
+
+func (d *Dist) validate(name string) error {
+	switch d.Type {
+	case "constant":
+	case "uniform":
+		if d.Min > d.Max || (!d.Int && d.Min == d.Max) {
+			return fmt.Errorf("%s: uniform requires min < max, got %v, %v", name, d.Min, d.Max)
+		}
+		if d.Int && (d.Min != math.Trunc(d.Min) || d.Max != math.Trunc(d.Max)) {
+			return fmt.Errorf("%s: integer uniform requires integer min, max, got %v, %v", name, d.Min, d.Max)
+		}
+	case "normal":
+		if d.std() <= 0 {
+			return fmt.Errorf("%s: normal requires std > 0 or mean * cv > 0", name)
+		}
+	case "gamma":
+		if d.Mean <= 0 || d.CV <= 0 {
+			return fmt.Errorf("%s: gamma requires mean > 0 and cv > 0, got %v, %v", name, d.Mean, d.CV)
+		}
+	case "lognormal":
+		if d.Sigma <= 0 {
+			return fmt.Errorf("%s: lognormal requires sigma > 0, got %v", name, d.Sigma)
+		}
+	case "pareto", "weibull":
+		if d.Scale <= 0 || d.Shape <= 0 {
+			return fmt.Errorf("%s: %s requires scale > 0 and shape > 0, got %v, %v", name, d.Type, d.Scale, d.Shape)
+		}
+	case "mixture":
+		if len(d.Components) == 0 {
+			return fmt.Errorf("%s: mixture requires components", name)
+		}
+		for i := range d.Components {
+			c := &d.Components[i]
+			cname := fmt.Sprintf("%s.components[%d]", name, i)
+			if c.Weight < 0 {
+				return fmt.Errorf("%s: weight must be >= 0, got %v", cname, c.Weight)
+			}
+			if err := c.validate(cname); err != nil {
+				return err
+			}
+		}
+		if d.totalWeight() <= 0 {
+			return fmt.Errorf("%s: sum of component weights must be > 0", name)
+		}
+	case "empirical":
+		if d.File == "" {
+			return fmt.Errorf("%s: empirical requires file", name)
+		}
+	default:
+		return fmt.Errorf("%s: unknown distribution type %q", name, d.Type)
+	}
+	if d.File != "" && d.Type != "empirical" {
+		return fmt.Errorf("%s: file is only allowed for empirical, got type %q", name, d.Type)
+	}
+	return nil
+}
+
+// nonNegative проверяет, что распределение не даёт отрицательных значений
+// (там, где это можно определить по параметрам)
+func (d *Dist) nonNegative(name string) error {
+	neg := false
+	switch d.Type {
+	case "constant":
+		neg = d.Value < 0
+	case "uniform":
+		neg = d.Min < 0
+	case "normal":
+		neg = true
+	case "empirical":
+		for _, v := range d.values {
+			neg = neg || v < 0
+		}
+	case "mixture":
+		for i := range d.Components {
+			if err := d.Components[i].nonNegative(fmt.Sprintf("%s.components[%d]", name, i)); err != nil {
+				return err
+			}
+		}
+	}
+	if neg {
+		return fmt.Errorf("%s: distribution must be non-negative, %s can produce negative values", name, d.Type)
+	}
+	return nil
+}
+
+// support — наименьшее и наибольшее значения распределения (±Inf, если не ограничено);
+// для gamma, lognormal и weibull нижняя граница 0 не достигается
+func (d *Dist) support() (lo, hi float64) {
+	switch d.Type {
+	case "constant":
+		lo, hi = d.Value, d.Value
+	case "uniform":
+		lo, hi = d.Min, d.Max
+	case "gamma", "lognormal", "weibull":
+		lo, hi = 0, math.Inf(1)
+	case "pareto":
+		lo, hi = d.Scale, math.Inf(1)
+	case "mixture":
+		lo, hi = math.Inf(1), math.Inf(-1)
+		for i := range d.Components {
+			if d.Components[i].Weight == 0 {
+				continue
+			}
+			clo, chi := d.Components[i].support()
+			lo, hi = min(lo, clo), max(hi, chi)
+		}
+	case "empirical":
+		lo, hi = slices.Min(d.values), slices.Max(d.values)
+	default: // normal
+		lo, hi = math.Inf(-1), math.Inf(1)
+	}
+	if d.Int {
+		lo, hi = math.Round(lo), math.Round(hi)
+	}
+	return lo, hi
+}
+
+type namedDist struct {
+	name string
+	dist *Dist
+}
+
+// dists — все распределения конфига с путями для сообщений об ошибках
+func (c *Config) dists() []namedDist {
+	ds := []namedDist{
+		{"traffic.fragments", &c.Traffic.Fragments},
+		{"cluster.capacity", &c.Cluster.Capacity},
+		{"cluster.owd", &c.Cluster.OWD},
+		{"cluster.service_noise", &c.Cluster.ServiceNoise},
+		{"cluster.redirect_penalty", &c.Cluster.RedirectPenalty},
+		{"degradation.duration", &c.Degradation.Duration},
+		{"degradation.bandwidth", &c.Degradation.Bandwidth},
+		{"degradation.latency", &c.Degradation.Latency},
+	}
+	if c.Traffic.Catalog.Length.IsSet() {
+		ds = append(ds, namedDist{"traffic.catalog.length", &c.Traffic.Catalog.Length})
+	}
+	if c.Traffic.Arrivals.BatchSize.IsSet() {
+		ds = append(ds, namedDist{"traffic.arrivals.batch_size", &c.Traffic.Arrivals.BatchSize})
+	}
+	for i := range c.Cluster.Pools {
+		p := &c.Cluster.Pools[i]
+		ds = append(ds,
+			namedDist{fmt.Sprintf("cluster.pools[%d].capacity", i), &p.Capacity},
+			namedDist{fmt.Sprintf("cluster.pools[%d].owd", i), &p.OWD})
+	}
+	return ds
+}
+
+// defaultFragments — смесь равномерных длин сессий: короткие клипы, эпизоды, фильмы, трансляции
+func defaultFragments() Dist {
+	uniform := func(max, weight float64) Dist {
+		return Dist{Type: "uniform", Min: 1, Max: max, Int: true, Weight: weight}
+	}
+	return Dist{Type: "mixture", Components: []Dist{
+		uniform(15, 0.55), uniform(100, 0.30), uniform(300, 0.10), uniform(900, 0.05),
+	}}
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/common"
+)
+
+func TestDistSample(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "lengths.csv", "fragments\n2\n4\n# comment\n\n6\n")
+	write(t, dir, "cfg.yaml", `traffic:
+  fragments:
+    type: mixture
+    components:
+      - {weight: 3, type: empirical, file: lengths.csv}
+      - {weight: 1, type: uniform, min: 10, max: 12, int: true}
+cluster:
+  redirect_penalty: {type: constant, value: 2.5}
+`)
+	cfg, err := Load(filepath.Join(dir, "cfg.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// (3 * 4 + 1 * 11) / 4
+	if got := cfg.Traffic.Fragments.Expected(); got != 5.75 {
+		t.Fatalf("expected fragments = %v, want 5.75", got)
+	}
+	rng := common.NewRNG(1)
+	allowed := map[float64]bool{2: true, 4: true, 6: true, 10: true, 11: true, 12: true}
+	sum := 0.0
+	const n = 20000
+	for range n {
+		x := cfg.Traffic.Fragments.Sample(rng)
+		if !allowed[x] {
+			t.Fatalf("unexpected sample %v", x)
+		}
+		sum += x
+	}
+	if mean := sum / n; math.Abs(mean-5.75) > 0.1 {
+		t.Fatalf("sample mean = %v, want about 5.75", mean)
+	}
+	if got := cfg.Cluster.RedirectPenalty.Sample(rng); got != 2.5 {
+		t.Fatalf("redirect penalty = %v, want 2.5", got)
+	}
+	// незаданные распределения строятся из прежних параметров cluster
+	if d := cfg.Cluster.Capacity; d.Type != "normal" || d.Mean != cfg.Cluster.CapMean {
+		t.Fatalf("default capacity = %+v", d)
+	}
+}
+
+func TestDistValidate(t *testing.T) {
+	cases := []struct {
+		yaml string
+		want string
+	}{
+		{"traffic:\n  fragments: {type: zipf}\n", `traffic.fragments: unknown distribution type "zipf"`},
+		{"traffic:\n  fragments: {type: uniform, min: 5, max: 1}\n", "traffic.fragments: uniform requires min < max"},
+		{"traffic:\n  fragments: {type: mixture, components: [{weight: 1, type: gamma, mean: 1}]}\n",
+			"traffic.fragments.components[0]: gamma requires mean > 0 and cv > 0"},
+		{"cluster:\n  redirect_penalty: {type: normal, mean: 100, cv: 0.1}\n", "cluster.redirect_penalty: distribution must be non-negative"},
+		{"cluster:\n  owd: {type: empirical}\n", "cluster.owd: empirical requires file"},
+		{"degradation:\n  bandwidth: {type: uniform, min: 0.2, max: 1.5}\n", "degradation.bandwidth must be in (0, 1]"},
+		{"degradation:\n  bandwidth: {type: gamma, mean: 0.5, cv: 0.2}\n", "degradation.bandwidth must be in (0, 1]"},
+		{"degradation:\n  latency: {type: lognormal, mu: 1, sigma: 0.5}\n", "degradation.latency must be >= 1"},
+		{"degradation:\n  latency: {type: mixture, components: [{weight: 1, type: constant, value: 2}, {weight: 1, type: pareto, scale: 0.5, shape: 2}]}\n",
+			"degradation.latency must be >= 1"},
+	}
+	for _, tc := range cases {
+		dir := t.TempDir()
+		write(t, dir, "cfg.yaml", tc.yaml)
+		_, err := Load(filepath.Join(dir, "cfg.yaml"))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("error = %v, want it to contain %q", err, tc.want)
+		}
+	}
+}
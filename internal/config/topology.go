@@ -0,0 +1,328 @@
+package config
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ServerSpec — сервер из файла топологии (topology.servers_file)
+type ServerSpec struct {
+	Row            int // номер строки (csv) или элемента (json), для сообщений об ошибках
+	ID             int
+	PoP            string
+	Region         string
+	Mbps           float64
+	OWD            float64
+	Jitter         float64 // СКО джиттера вокруг owd_ms (0 — owd постоянна)
+	MaxConnections int     // 0 — вычисляется из mbps и cluster.bitrate
+}
+
+// LatencySpec — задержка от региона клиента до сервера (server_id) или до региона серверов (server_region)
+type LatencySpec struct {
+	Row          int
+	Client       string
+	ServerID     int
+	ServerRegion string
+	OWD          float64
+}
+
+// record — строка файла: значения по именам колонок (csv) или ключам (json)
+type record struct {
+	row    int
+	values map[string]string
+}
+
+func (r record) str(key string) string {
+	return strings.TrimSpace(r.values[key])
+}
+
+func (r record) float(path, key string, required bool) (float64, error) {
+	v := r.str(key)
+	if v == "" {
+		if required {
+			return 0, fmt.Errorf("%s:%d: %s is required", path, r.row, key)
+		}
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%d: %s: invalid number %q", path, r.row, key, v)
+	}
+	return f, nil
+}
+
+func (r record) int(path, key string, required bool) (int, error) {
+	v := r.str(key)
+	if v == "" {
+		if required {
+			return 0, fmt.Errorf("%s:%d: %s is required", path, r.row, key)
+		}
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s:%d: %s: invalid integer %q", path, r.row, key, v)
+	}
+	return n, nil
+}
+
+// readRecords читает csv с заголовком, json-массив объектов или jsonl (объект в строке).
+// Для csv номер строки учитывает заголовок, для json — индекс элемента с 1.
+func readRecords(path string) ([]record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+		var records []record
+		dec := json.NewDecoder(f)
+		for row := 1; ; row++ {
+			var item map[string]any
+			if err := dec.Decode(&item); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, row, err)
+			}
+			records = append(records, record{row: row, values: stringify(item)})
+		}
+		return records, nil
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var items []map[string]any
+		if err := json.NewDecoder(f).Decode(&items); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		records := make([]record, len(items))
+		for i, item := range items {
+			records[i] = record{row: i + 1, values: stringify(item)}
+		}
+		return records, nil
+	}
+
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: cannot read header: %w", path, err)
+	}
+	var records []record
+	for row := 2; ; row++ {
+		fields, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, row, err)
+		}
+		values := make(map[string]string, len(header))
+		for i, h := range header {
+			values[strings.TrimSpace(h)] = fields[i]
+		}
+		records = append(records, record{row: row, values: values})
+	}
+	return records, nil
+}
+
+func stringify(item map[string]any) map[string]string {
+	values := make(map[string]string, len(item))
+	for k, v := range item {
+		values[k] = fmt.Sprint(v)
+	}
+	return values
+}
+
+func resolve(base, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(base, path)
+}
+
+// loadTopology загружает файлы topology.* (пути — относительно каталога конфига)
+// и пересчитывает размеры PoP по загруженным серверам
+func loadTopology(c *Config, base string) error {
+	c.Topology.ServersFile = resolve(base, c.Topology.ServersFile)
+	c.Topology.LatencyFile = resolve(base, c.Topology.LatencyFile)
+
+	if c.Topology.ServersFile != "" {
+		servers, err := loadServers(c.Topology.ServersFile)
+		if err != nil {
+			return err
+		}
+		c.Topology.Servers = servers
+
+		counts := make(map[string]int)
+		for _, s := range servers {
+			counts[s.PoP]++
+		}
+		for i := range c.PoPs {
+			c.PoPs[i].Servers = counts[c.PoPs[i].Name]
+		}
+		c.Cluster.Servers = len(servers)
+	}
+	if c.Topology.LatencyFile != "" {
+		latency, err := loadLatency(c.Topology.LatencyFile)
+		if err != nil {
+			return err
+		}
+		c.Topology.Latency = latency
+	}
+	return nil
+}
+
+func loadServers(path string) ([]ServerSpec, error) {
+	records, err := readRecords(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s: no servers", path)
+	}
+
+	servers := make([]ServerSpec, len(records))
+	seen := make(map[int]int, len(records))
+	for i, r := range records {
+		s := ServerSpec{Row: r.row, PoP: r.str("pop"), Region: r.str("region")}
+		if s.ID, err = r.int(path, "id", false); err != nil {
+			return nil, err
+		}
+		if s.ID == 0 {
+			s.ID = i + 1
+		}
+		if s.ID < 1 || s.ID > len(records) {
+			return nil, fmt.Errorf("%s:%d: id must be in 1..%d, got %d", path, r.row, len(records), s.ID)
+		}
+		if prev, ok := seen[s.ID]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate id %d (first at %d)", path, r.row, s.ID, prev)
+		}
+		seen[s.ID] = r.row
+		if s.Mbps, err = r.float(path, "mbps", true); err != nil {
+			return nil, err
+		}
+		if s.Mbps <= 0 {
+			return nil, fmt.Errorf("%s:%d: mbps must be > 0, got %v", path, r.row, s.Mbps)
+		}
+		if s.OWD, err = r.float(path, "owd_ms", true); err != nil {
+			return nil, err
+		}
+		if s.OWD < 0 {
+			return nil, fmt.Errorf("%s:%d: owd_ms must be >= 0, got %v", path, r.row, s.OWD)
+		}
+		if s.Jitter, err = r.float(path, "owd_jitter_ms", false); err != nil {
+			return nil, err
+		}
+		if s.Jitter < 0 {
+			return nil, fmt.Errorf("%s:%d: owd_jitter_ms must be >= 0, got %v", path, r.row, s.Jitter)
+		}
+		if s.MaxConnections, err = r.int(path, "max_conn", false); err != nil {
+			return nil, err
+		}
+		if s.MaxConnections < 0 {
+			return nil, fmt.Errorf("%s:%d: max_conn must be >= 0, got %d", path, r.row, s.MaxConnections)
+		}
+		servers[i] = s
+	}
+	return servers, nil
+}
+
+func loadLatency(path string) ([]LatencySpec, error) {
+	records, err := readRecords(path)
+	if err != nil {
+		return nil, err
+	}
+
+	latency := make([]LatencySpec, len(records))
+	for i, r := range records {
+		l := LatencySpec{Row: r.row, Client: r.str("client_region"), ServerRegion: r.str("server_region")}
+		if l.Client == "" {
+			return nil, fmt.Errorf("%s:%d: client_region is required", path, r.row)
+		}
+		if l.ServerID, err = r.int(path, "server_id", false); err != nil {
+			return nil, err
+		}
+		if (l.ServerID == 0) == (l.ServerRegion == "") {
+			return nil, fmt.Errorf("%s:%d: exactly one of server_id, server_region is required", path, r.row)
+		}
+		if l.OWD, err = r.float(path, "owd_ms", true); err != nil {
+			return nil, err
+		}
+		if l.OWD < 0 {
+			return nil, fmt.Errorf("%s:%d: owd_ms must be >= 0, got %v", path, r.row, l.OWD)
+		}
+		latency[i] = l
+	}
+	return latency, nil
+}
+
+// validateTopology сверяет загруженные файлы с pops и geo.regions
+// и переносит задержки в матрицу регионов и таблицу задержек до серверов
+func validateTopology(c *Config) error {
+	serversPath, latencyPath := c.Topology.ServersFile, c.Topology.LatencyFile
+
+	pops := make(map[string]bool, len(c.PoPs))
+	for _, p := range c.PoPs {
+		pops[p.Name] = true
+	}
+	for _, s := range c.Topology.Servers {
+		if len(c.PoPs) > 0 && !pops[s.PoP] {
+			return fmt.Errorf("%s:%d: unknown pop %q", serversPath, s.Row, s.PoP)
+		}
+		if len(c.PoPs) == 0 && s.PoP != "" {
+			return fmt.Errorf("%s:%d: pop %q set, but pops are not configured", serversPath, s.Row, s.PoP)
+		}
+		if _, ok := c.Geo.regionIdx[s.Region]; s.Region != "" && !ok {
+			return fmt.Errorf("%s:%d: unknown region %q", serversPath, s.Row, s.Region)
+		}
+	}
+
+	if len(c.Topology.Latency) > 0 && len(c.Geo.Regions) == 0 {
+		return fmt.Errorf("%s: geo.regions must be configured to use a latency file", latencyPath)
+	}
+	if len(c.Geo.Latency) == 0 {
+		c.Geo.Latency = make([][]float64, len(c.Geo.Regions))
+		for i := range c.Geo.Latency {
+			c.Geo.Latency[i] = make([]float64, len(c.Geo.Regions))
+		}
+	}
+	c.Geo.serverLatency = make(map[serverKey]float64)
+	for _, l := range c.Topology.Latency {
+		i, ok := c.Geo.regionIdx[l.Client]
+		if !ok {
+			return fmt.Errorf("%s:%d: unknown client_region %q", latencyPath, l.Row, l.Client)
+		}
+		if l.ServerID != 0 {
+			if l.ServerID < 1 || l.ServerID > c.Cluster.Servers {
+				return fmt.Errorf("%s:%d: server_id must be in 1..%d, got %d", latencyPath, l.Row, c.Cluster.Servers, l.ServerID)
+			}
+			c.Geo.serverLatency[serverKey{l.Client, l.ServerID}] = l.OWD
+			continue
+		}
+		j, ok := c.Geo.regionIdx[l.ServerRegion]
+		if !ok {
+			return fmt.Errorf("%s:%d: unknown server_region %q", latencyPath, l.Row, l.ServerRegion)
+		}
+		c.Geo.Latency[i][j] = l.OWD
+	}
+	return nil
+}
+
+type serverKey struct {
+	client   string
+	serverID int
+}
+
+// ClientLatency — задержка от региона клиента до конкретного сервера, мс:
+// из таблицы задержек до серверов, если есть, иначе — по матрице регионов
+func (c *Config) ClientLatency(client string, serverID int, serverRegion string) float64 {
+	if owd, ok := c.Geo.serverLatency[serverKey{client, serverID}]; ok {
+		return owd
+	}
+	return c.RegionLatency(client, serverRegion)
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTopologyErrorsPointToRow(t *testing.T) {
+	cases := []struct {
+		name    string
+		servers string
+		latency string
+		want    string
+	}{
+		{"bad number", "id,mbps,owd_ms\n1,100,10\n2,fast,10\n", "", "servers.csv:3: mbps"},
+		{"duplicate id", "id,mbps,owd_ms\n1,100,10\n1,100,10\n", "", "servers.csv:3: duplicate id 1"},
+		{"unknown region", "id,region,mbps,owd_ms\n1,eu,100,10\n2,asia,100,10\n", "", `servers.csv:3: unknown region "asia"`},
+		{"unknown server", "id,region,mbps,owd_ms\n1,eu,100,10\n",
+			"client_region,server_id,owd_ms\neu,1,5\neu,7,5\n", "latency.csv:3: server_id must be in 1..1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := "geo:\n  regions: [{name: eu, weight: 1}]\n  latency_ms: [[0]]\ntopology:\n  servers_file: servers.csv\n"
+			write(t, dir, "servers.csv", tc.servers)
+			if tc.latency != "" {
+				write(t, dir, "latency.csv", tc.latency)
+				cfg += "  latency_file: latency.csv\n"
+			}
+			write(t, dir, "cfg.yaml", cfg)
+
+			_, err := Load(filepath.Join(dir, "cfg.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("error = %v, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func write(t *testing.T, dir, name, data string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
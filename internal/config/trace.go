@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/emrzvv/lb-research/internal/common"
+)
+
+// TraceArrival — поступление сессии из трассы (traffic.trace.file)
+type TraceArrival struct {
+	Row       int
+	T         float64 // время в симуляции, сек
+	SessionID int64   // 0 — присваивается при воспроизведении
+	UserID    int64
+	ContentID int // 0 — без каталога
+	Fragments int
+	Region    string // без значения в трассе выбирается по geo.regions при загрузке
+}
+
+// traceRegionSeed отделяет генератор регионов трассы от основного генератора прогона
+const traceRegionSeed = 0x7472616365
+
+// column — первая из колонок keys, заданная в записи (в трассах встречаются разные имена)
+func (r record) column(keys ...string) string {
+	for _, k := range keys {
+		if v := r.str(k); v != "" {
+			return k
+		}
+	}
+	return keys[0]
+}
+
+// loadTrace загружает traffic.trace.file; формат совместим с arrivals.csv
+func loadTrace(c *Config, base string) error {
+	tr := &c.Traffic.Trace
+	if tr.File == "" {
+		return nil
+	}
+	tr.File = resolve(base, tr.File)
+	records, err := readRecords(tr.File)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%s: no arrivals", tr.File)
+	}
+
+	arrivals := make([]TraceArrival, len(records))
+	sessions := make(map[int64]int, len(records)) // session_id -> строка
+	for i, r := range records {
+		a := TraceArrival{Row: r.row, Region: r.str("region")}
+		if a.T, err = r.float(tr.File, r.column("time_s", "timestamp"), true); err != nil {
+			return err
+		}
+		// без user_id пользователем считается session_id (старые arrivals.csv),
+		// без session_id номер сессии присваивается при воспроизведении
+		user, err := r.int(tr.File, r.column("user_id", "session_id"), true)
+		if err != nil {
+			return err
+		}
+		if user < 1 {
+			return fmt.Errorf("%s:%d: user id must be >= 1, got %d", tr.File, r.row, user)
+		}
+		session, err := r.int(tr.File, "session_id", false)
+		if err != nil {
+			return err
+		}
+		if session < 0 {
+			return fmt.Errorf("%s:%d: session id must be >= 1, got %d", tr.File, r.row, session)
+		}
+		a.UserID, a.SessionID = int64(user), int64(session)
+		if session > 0 {
+			if prev, ok := sessions[a.SessionID]; ok {
+				return fmt.Errorf("%s:%d: duplicate session id %d (first at %d)", tr.File, r.row, session, prev)
+			}
+			sessions[a.SessionID] = r.row
+			tr.MaxSessionID = max(tr.MaxSessionID, a.SessionID)
+		}
+		if a.ContentID, err = r.int(tr.File, "content_id", false); err != nil {
+			return err
+		}
+		if a.ContentID < 0 {
+			return fmt.Errorf("%s:%d: content id must be >= 0, got %d", tr.File, r.row, a.ContentID)
+		}
+		if a.Fragments, err = r.int(tr.File, r.column("fragments", "segments"), true); err != nil {
+			return err
+		}
+		if a.Fragments < 1 {
+			return fmt.Errorf("%s:%d: fragments must be >= 1, got %d", tr.File, r.row, a.Fragments)
+		}
+		arrivals[i] = a
+	}
+	sort.SliceStable(arrivals, func(i, j int) bool { return arrivals[i].T < arrivals[j].T })
+	t0 := 0.0
+	if tr.Rebase {
+		t0 = arrivals[0].T
+	}
+	speed := 1.0
+	if tr.Speed > 0 {
+		speed = tr.Speed
+	}
+	for i := range arrivals {
+		arrivals[i].T = (arrivals[i].T - t0) / speed
+	}
+	tr.Arrivals = arrivals
+	return nil
+}
+
+// fillTraceRegions выбирает регионы поступлениям трассы без региона отдельным генератором
+// от simulation.seed: выбор не зависит от порядка обращений к общему генератору при прогоне
+func fillTraceRegions(c *Config) {
+	if len(c.Geo.Regions) == 0 {
+		return
+	}
+	rng := common.NewRNG(c.Simulation.Seed ^ traceRegionSeed)
+	for i := range c.Traffic.Trace.Arrivals {
+		if a := &c.Traffic.Trace.Arrivals[i]; a.Region == "" {
+			a.Region = c.ChooseRegion(rng)
+		}
+	}
+}
+
+// validateTrace проверяет время и регионы трассы
+func validateTrace(c *Config) error {
+	tr := &c.Traffic.Trace
+	if tr.File == "" {
+		return nil
+	}
+	if tr.Speed <= 0 {
+		return fmt.Errorf("traffic.trace.speed must be > 0, got %v", tr.Speed)
+	}
+	for i := range tr.Arrivals {
+		a := &tr.Arrivals[i]
+		if a.T < 0 {
+			return fmt.Errorf("%s:%d: time must be >= 0 (use rebase for absolute timestamps), got %v", tr.File, a.Row, a.T)
+		}
+		if a.Region == "" {
+			continue
+		}
+		if _, ok := c.Geo.regionIdx[a.Region]; !ok {
+			return fmt.Errorf("%s:%d: unknown region %q", tr.File, a.Row, a.Region)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTraceLoad(t *testing.T) {
+	dir := t.TempDir()
+	// формат arrivals.csv из export
+	write(t, dir, "arrivals.csv", "time_s,session_id,user_id,content_id,fragments,region\n0.50000,7,7,12,3,eu\n0.25000,9,4,0,1,\n")
+	// трасса с другими именами колонок и абсолютным временем
+	write(t, dir, "prod.jsonl", `{"timestamp": 1000, "user_id": 1, "segments": 4}
+{"timestamp": 1010, "user_id": 2, "segments": 2, "region": "eu"}
+`)
+	geo := "geo:\n  regions: [{name: eu, weight: 1}]\n  latency_ms: [[0]]\n"
+	write(t, dir, "csv.yaml", geo+"traffic:\n  trace: {file: arrivals.csv}\n")
+	write(t, dir, "jsonl.yaml", geo+"traffic:\n  trace: {file: prod.jsonl, rebase: true, speed: 2}\n")
+
+	cfg, err := Load(filepath.Join(dir, "csv.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := cfg.Traffic.Trace.Arrivals
+	if len(got) != 2 || got[0].SessionID != 9 || got[0].UserID != 4 || got[0].T != 0.25 || got[1].Region != "eu" || got[1].Fragments != 3 || got[1].ContentID != 12 {
+		t.Fatalf("csv arrivals = %+v", got)
+	}
+	if cfg.Traffic.Trace.MaxSessionID != 9 {
+		t.Fatalf("max session id = %d, want 9", cfg.Traffic.Trace.MaxSessionID)
+	}
+
+	cfg, err = Load(filepath.Join(dir, "jsonl.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = cfg.Traffic.Trace.Arrivals
+	if len(got) != 2 || got[0].T != 0 || got[1].T != 5 || got[1].UserID != 2 || got[1].SessionID != 0 || got[0].Fragments != 4 {
+		t.Fatalf("jsonl arrivals = %+v", got)
+	}
+
+	write(t, dir, "bad.csv", "time_s,session_id,fragments,region\n1,1,2,asia\n")
+	write(t, dir, "bad.yaml", geo+"traffic:\n  trace: {file: bad.csv}\n")
+	if _, err := Load(filepath.Join(dir, "bad.yaml")); err == nil || !strings.Contains(err.Error(), `bad.csv:2: unknown region "asia"`) {
+		t.Fatalf("error = %v, want unknown region at bad.csv:2", err)
+	}
+
+	write(t, dir, "dup.csv", "time_s,session_id,user_id,fragments\n1,5,1,2\n2,,1,2\n3,5,2,2\n")
+	write(t, dir, "dup.yaml", "traffic:\n  trace: {file: dup.csv}\n")
+	if _, err := Load(filepath.Join(dir, "dup.yaml")); err == nil || !strings.Contains(err.Error(), "dup.csv:4: duplicate session id 5 (first at 2)") {
+		t.Fatalf("error = %v, want duplicate session id at dup.csv:4", err)
+	}
+}
+
+func TestTraceRegionsAssignedAtLoad(t *testing.T) {
+	dir := t.TempDir()
+	var trace strings.Builder
+	trace.WriteString("time_s,user_id,fragments,region\n")
+	for i := range 200 {
+		region := ""
+		if i%10 == 0 {
+			region = "us"
+		}
+		fmt.Fprintf(&trace, "%d,%d,1,%s\n", i, i+1, region)
+	}
+	write(t, dir, "trace.csv", trace.String())
+	write(t, dir, "cfg.yaml", `simulation: {seed: 5}
+geo:
+  regions: [{name: eu, weight: 3}, {name: us, weight: 1}]
+  latency_ms: [[0, 80], [80, 0]]
+traffic:
+  trace: {file: trace.csv}
+`)
+
+	var runs [2][]string
+	for i := range runs {
+		cfg, err := Load(filepath.Join(dir, "cfg.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range cfg.Traffic.Trace.Arrivals {
+			runs[i] = append(runs[i], a.Region)
+		}
+	}
+	// регионы из трассы сохраняются, остальные разыгрываются одинаково при каждой загрузке
+	eu := 0
+	for i, region := range runs[0] {
+		if region != runs[1][i] {
+			t.Fatalf("arrival %d: region %q, then %q", i, region, runs[1][i])
+		}
+		if i%10 == 0 && region != "us" {
+			t.Fatalf("arrival %d: region %q, want us from the trace", i, region)
+		}
+		if region == "eu" {
+			eu++
+		}
+	}
+	if eu < 110 || eu > 160 {
+		t.Fatalf("eu arrivals = %d of 180 without region, want about 135", eu)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/csv"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/emrzvv/lb-research/internal/model"
@@ -17,13 +18,18 @@ func writeServersCfgToCSV(servers []*model.Server, path string) error {
 	}
 	defer f.Close()
 	w := csv.NewWriter(f)
-	_ = w.Write([]string{"id", "mbps", "owd_ms", "max_conn"})
+	_ = w.Write([]string{"id", "mbps", "owd_ms", "max_conn", "pop", "region", "pool", "labels", "domains"})
 	for _, s := range servers {
 		w.Write([]string{
 			fmt.Sprintf("%d", s.ID),
 			fmt.Sprintf("%.1f", s.Parameters.Mbps),
 			fmt.Sprintf("%.1f", s.Parameters.OWD),
 			fmt.Sprintf("%d", s.Parameters.MaxConnections),
+			s.PoP,
+			s.Region,
+			s.Pool,
+			strings.Join(s.Labels, ";"),
+			strings.Join(s.Domains, ";"),
 		})
 	}
 	w.Flush()
@@ -79,6 +85,342 @@ func writeSummaryToCSV(stats *stats.Statistics, servers []*model.Server, path, p
 	return wd.Error()
 }
 
+func writePoPsSummaryToCSV(stats *stats.Statistics, servers []*model.Server, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	type popSummary struct {
+		servers, picked, served, dropped, spillOut, spillIn int
+	}
+	var names []string
+	byName := make(map[string]*popSummary)
+	popOf := make(map[int]*popSummary, len(servers))
+	for _, s := range servers {
+		ps, ok := byName[s.PoP]
+		if !ok {
+			ps = &popSummary{}
+			byName[s.PoP] = ps
+			names = append(names, s.PoP)
+		}
+		ps.servers++
+		ps.picked += stats.Picks[s.ID-1]
+		popOf[s.ID] = ps
+	}
+	for _, r := range stats.ServerRequests {
+		popOf[r.ServerID].served++
+	}
+	for _, d := range stats.Drops {
+		if d.ServerID != 0 {
+			popOf[d.ServerID].dropped++
+		}
+	}
+	for _, sp := range stats.Spillovers {
+		byName[sp.FromPoP].spillOut++
+		byName[sp.ToPoP].spillIn++
+	}
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"pop", "servers", "picked", "served", "dropped", "spill_out", "spill_in"})
+	for _, name := range names {
+		ps := byName[name]
+		w.Write([]string{
+			name,
+			fmt.Sprintf("%d", ps.servers),
+			fmt.Sprintf("%d", ps.picked),
+			fmt.Sprintf("%d", ps.served),
+			fmt.Sprintf("%d", ps.dropped),
+			fmt.Sprintf("%d", ps.spillOut),
+			fmt.Sprintf("%d", ps.spillIn),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writePoolsSummaryToCSV(stats *stats.Statistics, servers []*model.Server, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	type poolSummary struct {
+		labels                  []string
+		servers, maxConn        int
+		mbps                    float64
+		picked, served, dropped int
+	}
+	var names []string
+	byName := make(map[string]*poolSummary)
+	poolOf := make(map[int]*poolSummary, len(servers))
+	for _, s := range servers {
+		ps, ok := byName[s.Pool]
+		if !ok {
+			ps = &poolSummary{labels: s.Labels}
+			byName[s.Pool] = ps
+			names = append(names, s.Pool)
+		}
+		ps.servers++
+		ps.mbps += s.Parameters.Mbps
+		ps.maxConn += s.Parameters.MaxConnections
+		ps.picked += stats.Picks[s.ID-1]
+		poolOf[s.ID] = ps
+	}
+	for _, r := range stats.ServerRequests {
+		poolOf[r.ServerID].served++
+	}
+	for _, d := range stats.Drops {
+		if d.ServerID != 0 {
+			poolOf[d.ServerID].dropped++
+		}
+	}
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"pool", "labels", "servers", "mbps", "max_conn", "picked", "served", "dropped"})
+	for _, name := range names {
+		ps := byName[name]
+		w.Write([]string{
+			name,
+			strings.Join(ps.labels, ";"),
+			fmt.Sprintf("%d", ps.servers),
+			fmt.Sprintf("%.1f", ps.mbps),
+			fmt.Sprintf("%d", ps.maxConn),
+			fmt.Sprintf("%d", ps.picked),
+			fmt.Sprintf("%d", ps.served),
+			fmt.Sprintf("%d", ps.dropped),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// writeDomainsSummaryToCSV — сводка по доменам отказа: сервер может входить в несколько доменов
+func writeDomainsSummaryToCSV(stats *stats.Statistics, servers []*model.Server, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	type domainSummary struct {
+		servers, failures, spikes            int
+		downS, spikeS                        float64
+		picked, served, dropped, droppedDown int
+	}
+	var names []string
+	byName := make(map[string]*domainSummary)
+	domainsOf := make(map[int][]*domainSummary, len(servers))
+	for _, s := range servers {
+		for _, name := range s.Domains {
+			ds, ok := byName[name]
+			if !ok {
+				ds = &domainSummary{}
+				byName[name] = ds
+				names = append(names, name)
+			}
+			ds.servers++
+			ds.picked += stats.Picks[s.ID-1]
+			domainsOf[s.ID] = append(domainsOf[s.ID], ds)
+		}
+	}
+	for _, ev := range stats.DomainEvents {
+		ds := byName[ev.Domain]
+		if ds == nil {
+			continue
+		}
+		switch ev.Kind {
+		case "fail":
+			ds.failures++
+			ds.downS += ev.T2 - ev.T1
+		case "spike":
+			ds.spikes++
+			ds.spikeS += ev.T2 - ev.T1
+		}
+	}
+	for _, r := range stats.ServerRequests {
+		for _, ds := range domainsOf[r.ServerID] {
+			ds.served++
+		}
+	}
+	for _, d := range stats.Drops {
+		for _, ds := range domainsOf[d.ServerID] {
+			ds.dropped++
+			if d.Reason == "server_down" {
+				ds.droppedDown++
+			}
+		}
+	}
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"domain", "servers", "failures", "down_s", "spikes", "spike_s",
+		"picked", "served", "dropped", "dropped_down"})
+	for _, name := range names {
+		ds := byName[name]
+		w.Write([]string{
+			name,
+			fmt.Sprintf("%d", ds.servers),
+			fmt.Sprintf("%d", ds.failures),
+			fmt.Sprintf("%.1f", ds.downS),
+			fmt.Sprintf("%d", ds.spikes),
+			fmt.Sprintf("%.1f", ds.spikeS),
+			fmt.Sprintf("%d", ds.picked),
+			fmt.Sprintf("%d", ds.served),
+			fmt.Sprintf("%d", ds.dropped),
+			fmt.Sprintf("%d", ds.droppedDown),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// writeCacheToCSV — попадания в кэш по серверам; строка all — по кластеру
+func writeCacheToCSV(servers []*model.Server, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"server_id", "hits", "misses", "hit_ratio", "origin_bytes"})
+	row := func(id string, hits, misses int, origin float64) {
+		ratio := 0.0
+		if hits+misses > 0 {
+			ratio = float64(hits) / float64(hits+misses)
+		}
+		w.Write([]string{
+			id,
+			fmt.Sprintf("%d", hits),
+			fmt.Sprintf("%d", misses),
+			fmt.Sprintf("%.5f", ratio),
+			fmt.Sprintf("%.0f", origin),
+		})
+	}
+	hits, misses, origin := 0, 0, 0.0
+	for _, s := range servers {
+		row(fmt.Sprintf("%d", s.ID), s.CacheHits, s.CacheMisses, s.OriginBytes)
+		hits += s.CacheHits
+		misses += s.CacheMisses
+		origin += s.OriginBytes
+	}
+	row("all", hits, misses, origin)
+	w.Flush()
+	return w.Error()
+}
+
+func writeStatesToCSV(stats *stats.Statistics, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"time_s", "server_id", "state"})
+	for _, se := range stats.States {
+		w.Write([]string{
+			fmt.Sprintf("%.5f", se.T),
+			fmt.Sprintf("%d", se.ServerID),
+			se.State,
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeDegradationsToCSV(stats *stats.Statistics, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"start_s", "end_s", "server_id", "label", "bandwidth", "latency"})
+	for _, de := range stats.Degradations {
+		w.Write([]string{
+			fmt.Sprintf("%.5f", de.T1),
+			fmt.Sprintf("%.5f", de.T2),
+			fmt.Sprintf("%d", de.ServerID),
+			de.Label,
+			fmt.Sprintf("%.4f", de.Bandwidth),
+			fmt.Sprintf("%.4f", de.Latency),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeRateToCSV(stats *stats.Statistics, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"time_s", "rate_rps"})
+	for _, re := range stats.Rates {
+		w.Write([]string{
+			fmt.Sprintf("%.5f", re.T),
+			fmt.Sprintf("%.4f", re.RPS),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeScalingToCSV(stats *stats.Statistics, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"time_s", "active", "provisioning", "utilisation", "action"})
+	for _, se := range stats.Scaling {
+		w.Write([]string{
+			fmt.Sprintf("%.5f", se.T),
+			fmt.Sprintf("%d", se.Active),
+			fmt.Sprintf("%d", se.Provisioning),
+			fmt.Sprintf("%.4f", se.Utilisation),
+			se.Action,
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeScenarioToCSV(stats *stats.Statistics, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"time_s", "action", "servers", "value", "undo"})
+	for _, se := range stats.Scenario {
+		ids := make([]string, len(se.Servers))
+		for i, id := range se.Servers {
+			ids[i] = fmt.Sprintf("%d", id)
+		}
+		w.Write([]string{
+			fmt.Sprintf("%.5f", se.T),
+			se.Action,
+			strings.Join(ids, ";"),
+			se.Value,
+			fmt.Sprintf("%t", se.Undo),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
 func writeSnapshotsToCSV(servers []*model.Server, path string) error {
 	f, err := os.Create(path)
 	if err != nil {
@@ -87,7 +429,7 @@ func writeSnapshotsToCSV(servers []*model.Server, path string) error {
 	defer f.Close()
 
 	wr := csv.NewWriter(f)
-	_ = wr.Write([]string{"time_s", "server_id", "connections", "owd_ms"})
+	_ = wr.Write([]string{"time_s", "server_id", "connections", "sessions", "queue", "owd_ms"})
 
 	for _, s := range servers {
 		for _, snap := range s.Snapshots {
@@ -95,6 +437,8 @@ func writeSnapshotsToCSV(servers []*model.Server, path string) error {
 				fmt.Sprintf("%.5f", snap.T),
 				fmt.Sprintf("%d", s.ID),
 				fmt.Sprintf("%d", snap.Connections),
+				fmt.Sprintf("%d", snap.Sessions),
+				fmt.Sprintf("%d", snap.Queue),
 				fmt.Sprintf("%.5f", snap.OWD),
 			})
 		}
@@ -114,11 +458,15 @@ func writeStatisticsToCSV(stats *stats.Statistics,
 	}
 
 	aw := csv.NewWriter(fa)
-	_ = aw.Write([]string{"time_s", "session_id"})
+	_ = aw.Write([]string{"time_s", "session_id", "user_id", "content_id", "fragments", "region"})
 	for _, event := range stats.Arrivals {
 		aw.Write([]string{
 			fmt.Sprintf("%.5f", event.T),
 			fmt.Sprintf("%d", event.SessionID),
+			fmt.Sprintf("%d", event.UserID),
+			fmt.Sprintf("%d", event.ContentID),
+			fmt.Sprintf("%d", event.Fragments),
+			event.Region,
 		})
 	}
 	aw.Flush()
@@ -154,14 +502,16 @@ func writeStatisticsToCSV(stats *stats.Statistics,
 	fd.Close()
 
 	rw := csv.NewWriter(fr)
-	_ = rw.Write([]string{"server_id", "session_id", "start_s", "end_s", "duration"})
+	_ = rw.Write([]string{"server_id", "session_id", "content_id", "start_s", "end_s", "duration", "service"})
 	for _, event := range stats.ServerRequests {
 		rw.Write([]string{
 			fmt.Sprintf("%d", event.ServerID),
 			fmt.Sprintf("%d", event.SessiontID),
+			fmt.Sprintf("%d", event.ContentID),
 			fmt.Sprintf("%.5f", event.T1),
 			fmt.Sprintf("%.5f", event.T2),
 			fmt.Sprintf("%.5f", event.Duration),
+			fmt.Sprintf("%.5f", event.Service),
 		})
 	}
 	rw.Flush()
@@ -175,13 +525,14 @@ func writeStatisticsToCSV(stats *stats.Statistics,
 		return err
 	}
 	rwred := csv.NewWriter(frd)
-	_ = rwred.Write([]string{"session_id", "from_id", "to_id", "time_s"})
+	_ = rwred.Write([]string{"session_id", "from_id", "to_id", "time_s", "reason"})
 	for _, ev := range stats.Redirects {
 		rwred.Write([]string{
 			fmt.Sprintf("%d", ev.SessionID),
 			fmt.Sprintf("%d", ev.FromID),
 			fmt.Sprintf("%d", ev.ToID),
 			fmt.Sprintf("%.5f", ev.T),
+			ev.Reason,
 		})
 	}
 	rwred.Flush()
@@ -189,6 +540,48 @@ func writeStatisticsToCSV(stats *stats.Statistics,
 	return rwred.Error()
 }
 
+func writePosteriorsToCSV(stats *stats.Statistics, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"time_s", "server_id", "alpha", "beta"})
+	for _, ev := range stats.Posteriors {
+		w.Write([]string{
+			fmt.Sprintf("%.5f", ev.T),
+			fmt.Sprintf("%d", ev.ServerID),
+			fmt.Sprintf("%.5f", ev.Alpha),
+			fmt.Sprintf("%.5f", ev.Beta),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeForecastsToCSV(stats *stats.Statistics, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"time_s", "actual_rps", "forecast_rps", "spreading"})
+	for _, ev := range stats.Forecasts {
+		w.Write([]string{
+			fmt.Sprintf("%.5f", ev.T),
+			fmt.Sprintf("%.5f", ev.Actual),
+			fmt.Sprintf("%.5f", ev.Forecast),
+			fmt.Sprintf("%t", ev.Spreading),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
 func ToCSV(dir string, statistics *stats.Statistics, servers []*model.Server) error {
 	err := os.MkdirAll(dir, 0o755)
 	if err != nil {
@@ -208,6 +601,30 @@ func ToCSV(dir string, statistics *stats.Statistics, servers []*model.Server) er
 	if err != nil {
 		return err
 	}
+	if len(servers) > 0 && servers[0].PoP != "" {
+		err = writePoPsSummaryToCSV(statistics, servers, fmt.Sprintf("%s/pops.csv", dir))
+		if err != nil {
+			return err
+		}
+	}
+	if len(servers) > 0 && servers[0].Pool != "" {
+		err = writePoolsSummaryToCSV(statistics, servers, fmt.Sprintf("%s/pools.csv", dir))
+		if err != nil {
+			return err
+		}
+	}
+	if slices.ContainsFunc(servers, func(s *model.Server) bool { return len(s.Domains) > 0 }) {
+		err = writeDomainsSummaryToCSV(statistics, servers, fmt.Sprintf("%s/domains.csv", dir))
+		if err != nil {
+			return err
+		}
+	}
+	if len(servers) > 0 && servers[0].HasCache() {
+		err = writeCacheToCSV(servers, fmt.Sprintf("%s/cache.csv", dir))
+		if err != nil {
+			return err
+		}
+	}
 	err = writeSnapshotsToCSV(servers, fmt.Sprintf("%s/snapshots.csv", dir))
 	if err != nil {
 		return err
@@ -220,5 +637,45 @@ func ToCSV(dir string, statistics *stats.Statistics, servers []*model.Server) er
 	if err != nil {
 		return err
 	}
+	if len(statistics.Posteriors) > 0 {
+		err = writePosteriorsToCSV(statistics, fmt.Sprintf("%s/posteriors.csv", dir))
+		if err != nil {
+			return err
+		}
+	}
+	if len(statistics.States) > 0 {
+		err = writeStatesToCSV(statistics, fmt.Sprintf("%s/failures.csv", dir))
+		if err != nil {
+			return err
+		}
+	}
+	if len(statistics.Degradations) > 0 {
+		err = writeDegradationsToCSV(statistics, fmt.Sprintf("%s/degradations.csv", dir))
+		if err != nil {
+			return err
+		}
+	}
+	err = writeRateToCSV(statistics, fmt.Sprintf("%s/rate.csv", dir))
+	if err != nil {
+		return err
+	}
+	if len(statistics.Scaling) > 0 {
+		err = writeScalingToCSV(statistics, fmt.Sprintf("%s/scaling.csv", dir))
+		if err != nil {
+			return err
+		}
+	}
+	if len(statistics.Scenario) > 0 {
+		err = writeScenarioToCSV(statistics, fmt.Sprintf("%s/scenario.csv", dir))
+		if err != nil {
+			return err
+		}
+	}
+	if len(statistics.Forecasts) > 0 {
+		err = writeForecastsToCSV(statistics, fmt.Sprintf("%s/forecast.csv", dir))
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
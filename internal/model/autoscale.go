@@ -0,0 +1,57 @@
+package model
+
+import (
+	"github.com/emrzvv/lb-research/internal/stats"
+)
+
+// PowerOn включает сервер из резерва автомасштабирования
+func (s *Server) PowerOn(now float64, st *stats.Statistics) {
+	s.mu.Lock()
+	if s.State != StateOff {
+		s.mu.Unlock()
+		return
+	}
+	s.State = StateActive
+	s.mu.Unlock()
+
+	st.AddState(&stats.StateEvent{T: now, ServerID: s.ID, State: StateActive.String()})
+}
+
+// PowerOff возвращает сервер в резерв; сервер выводится заранее (Drain),
+// оставшиеся запросы завершаются отказом server_down. Причины выключения
+// сбрасываются: включённый из резерва сервер начинает с чистого состояния
+func (s *Server) PowerOff(now float64, st *stats.Statistics) {
+	s.mu.Lock()
+	if s.State == StateOff {
+		s.mu.Unlock()
+		return
+	}
+	s.State = StateOff
+	s.downBy = nil
+	s.abort()
+	s.mu.Unlock()
+
+	st.AddState(&stats.StateEvent{T: now, ServerID: s.ID, State: StateOff.String()})
+}
+
+// IsOff — сервер в резерве автомасштабирования
+func (s *Server) IsOff() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.State == StateOff
+}
+
+// SnapshotLoad — занятые соединения (с очередью) и лимит соединений по последнему снимку
+func (s *Server) SnapshotLoad() (used, capacity int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.Snapshots) == 0 {
+		return 0, s.Parameters.MaxConnections
+	}
+	ss := s.Snapshots[len(s.Snapshots)-1]
+	used = ss.Connections
+	if s.Parameters.SessionSlots {
+		used = ss.Sessions
+	}
+	return used + ss.Queue, s.Parameters.MaxConnections
+}
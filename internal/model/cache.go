@@ -0,0 +1,253 @@
+package model
+
+import (
+	"container/heap"
+	"container/list"
+
+	"github.com/emrzvv/lb-research/internal/config"
+)
+
+// segmentKey — фрагмент ролика; без каталога ролик — сама сессия (content < 0)
+type segmentKey struct {
+	content int64
+	segment int
+}
+
+// segmentCache — политика вытеснения кэша фрагментов (cluster.cache.policy);
+// фрагменты одного размера, поэтому ёмкость считается во фрагментах
+type segmentCache interface {
+	// get — обращение к фрагменту; true — попадание
+	get(k segmentKey) bool
+	// add помещает фрагмент в кэш после промаха
+	add(k segmentKey)
+}
+
+func newSegmentCache(cfg *config.Config) segmentCache {
+	capacity := int(cfg.Cluster.Cache.Bytes / cfg.Cluster.SegmentSizeBytes)
+	switch cfg.Cluster.Cache.Policy {
+	case "lfu":
+		return &lfuCache{capacity: capacity, items: make(map[segmentKey]*lfuItem)}
+	case "s3fifo":
+		return newS3FIFOCache(capacity)
+	}
+	return &lruCache{capacity: capacity, order: list.New(), items: make(map[segmentKey]*list.Element)}
+}
+
+// HasCache — задан ли кэш фрагментов сервера
+func (s *Server) HasCache() bool {
+	return s.cache != nil
+}
+
+// lookup — обращение к кэшу сервера; true — промах, фрагмент нужно загрузить
+// с origin (s.origin); вызывается под s.mu
+func (s *Server) lookup(cfg *config.Config, session *Session) bool {
+	if s.cache == nil {
+		return false
+	}
+	k := segmentKey{content: int64(session.ContentID), segment: session.Segment}
+	if session.ContentID == 0 {
+		k.content = -session.ID
+	}
+	if s.cache.get(k) {
+		s.CacheHits++
+		return false
+	}
+	s.CacheMisses++
+	s.cache.add(k)
+	s.OriginBytes += cfg.Cluster.SegmentSizeBytes
+	return true
+}
+
+// lruCache вытесняет давно не запрашивавшийся фрагмент
+type lruCache struct {
+	capacity int
+	order    *list.List // от недавних к давним
+	items    map[segmentKey]*list.Element
+}
+
+func (c *lruCache) get(k segmentKey) bool {
+	e, ok := c.items[k]
+	if ok {
+		c.order.MoveToFront(e)
+	}
+	return ok
+}
+
+func (c *lruCache) add(k segmentKey) {
+	if c.capacity == 0 {
+		return
+	}
+	if c.order.Len() >= c.capacity {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.items, last.Value.(segmentKey))
+	}
+	c.items[k] = c.order.PushFront(k)
+}
+
+// lfuCache вытесняет наименее часто запрашиваемый фрагмент, при равенстве — давний
+type lfuCache struct {
+	capacity int
+	tick     int
+	items    map[segmentKey]*lfuItem
+	heap     lfuHeap
+}
+
+type lfuItem struct {
+	key   segmentKey
+	freq  int
+	tick  int // момент последнего обращения
+	index int
+}
+
+type lfuHeap []*lfuItem
+
+func (h lfuHeap) Len() int { return len(h) }
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *lfuHeap) Push(x any) {
+	it := x.(*lfuItem)
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+func (h *lfuHeap) Pop() any {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
+
+func (c *lfuCache) get(k segmentKey) bool {
+	it, ok := c.items[k]
+	if ok {
+		c.tick++
+		it.freq++
+		it.tick = c.tick
+		heap.Fix(&c.heap, it.index)
+	}
+	return ok
+}
+
+func (c *lfuCache) add(k segmentKey) {
+	if c.capacity == 0 {
+		return
+	}
+	if len(c.heap) >= c.capacity {
+		it := heap.Pop(&c.heap).(*lfuItem)
+		delete(c.items, it.key)
+	}
+	c.tick++
+	it := &lfuItem{key: k, freq: 1, tick: c.tick}
+	heap.Push(&c.heap, it)
+	c.items[k] = it
+}
+
+// s3fifoCache — S3-FIFO: новые фрагменты попадают в малую очередь (10% ёмкости),
+// в основную переходят запрошенные повторно и недавно вытесненные (ghost-очередь)
+type s3fifoCache struct {
+	smallCap, mainCap int
+	small, main       *list.List // от новых к старым
+	ghost             *list.List
+	items             map[segmentKey]*s3fifoItem
+	ghosts            map[segmentKey]*list.Element
+}
+
+type s3fifoItem struct {
+	key  segmentKey
+	freq int // 0..3
+}
+
+func newS3FIFOCache(capacity int) *s3fifoCache {
+	small := max(capacity/10, 1)
+	if capacity < 2 {
+		small = 0
+	}
+	return &s3fifoCache{
+		smallCap: small,
+		mainCap:  capacity - small,
+		small:    list.New(),
+		main:     list.New(),
+		ghost:    list.New(),
+		items:    make(map[segmentKey]*s3fifoItem),
+		ghosts:   make(map[segmentKey]*list.Element),
+	}
+}
+
+func (c *s3fifoCache) get(k segmentKey) bool {
+	it, ok := c.items[k]
+	if ok {
+		it.freq = min(it.freq+1, 3)
+	}
+	return ok
+}
+
+func (c *s3fifoCache) add(k segmentKey) {
+	if c.smallCap+c.mainCap == 0 {
+		return
+	}
+	if e, ok := c.ghosts[k]; ok {
+		c.ghost.Remove(e)
+		delete(c.ghosts, k)
+		c.insertMain(&s3fifoItem{key: k})
+		return
+	}
+	if c.smallCap == 0 {
+		c.insertMain(&s3fifoItem{key: k})
+		return
+	}
+	for c.small.Len() >= c.smallCap {
+		c.evictSmall()
+	}
+	it := &s3fifoItem{key: k}
+	c.small.PushFront(it)
+	c.items[k] = it
+}
+
+func (c *s3fifoCache) insertMain(it *s3fifoItem) {
+	for c.main.Len() >= c.mainCap {
+		c.evictMain()
+	}
+	c.main.PushFront(it)
+	c.items[it.key] = it
+}
+
+// evictSmall: повторно запрошенный фрагмент переходит в основную очередь,
+// остальные вытесняются с запоминанием в ghost
+func (c *s3fifoCache) evictSmall() {
+	it := c.small.Remove(c.small.Back()).(*s3fifoItem)
+	if it.freq > 0 {
+		it.freq = 0
+		c.insertMain(it)
+		return
+	}
+	delete(c.items, it.key)
+	if c.ghost.Len() >= c.mainCap {
+		old := c.ghost.Remove(c.ghost.Back()).(segmentKey)
+		delete(c.ghosts, old)
+	}
+	c.ghosts[it.key] = c.ghost.PushFront(it.key)
+}
+
+// evictMain: фрагмент с ненулевой частотой возвращается в начало очереди с freq-1
+func (c *s3fifoCache) evictMain() {
+	for {
+		e := c.main.Back()
+		it := e.Value.(*s3fifoItem)
+		if it.freq == 0 {
+			c.main.Remove(e)
+			delete(c.items, it.key)
+			return
+		}
+		it.freq--
+		c.main.MoveToFront(e)
+	}
+}
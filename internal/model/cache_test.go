@@ -0,0 +1,109 @@
+package model
+
+import (
+	"math"
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/fschuetz04/simgo"
+)
+
+func cacheFor(policy string, segments int) segmentCache {
+	cfg := &config.Config{}
+	cfg.Cluster.SegmentSizeBytes = 1000
+	cfg.Cluster.Cache.Policy = policy
+	cfg.Cluster.Cache.Bytes = float64(segments) * 1000
+	return newSegmentCache(cfg)
+}
+
+// request — обращение к фрагменту с загрузкой при промахе, как в Server.lookup
+func request(c segmentCache, content int64) bool {
+	k := segmentKey{content: content}
+	if c.get(k) {
+		return true
+	}
+	c.add(k)
+	return false
+}
+
+func TestCachePolicies(t *testing.T) {
+	// LRU: вытесняется давний фрагмент
+	c := cacheFor("lru", 2)
+	request(c, 1)
+	request(c, 2)
+	request(c, 1)
+	request(c, 3) // вытесняет 2
+	if !request(c, 1) || request(c, 2) {
+		t.Fatalf("lru: want 1 cached and 2 evicted")
+	}
+
+	// LFU: часто запрашиваемый фрагмент переживает более свежие
+	c = cacheFor("lfu", 2)
+	for range 3 {
+		request(c, 1)
+	}
+	request(c, 2)
+	request(c, 3) // вытесняет 2 (freq 1 против 3)
+	if !request(c, 1) || request(c, 2) {
+		t.Fatalf("lfu: want 1 cached and 2 evicted")
+	}
+
+	// S3-FIFO: однократные запросы (скан) не вытесняют горячие фрагменты
+	c = cacheFor("s3fifo", 10)
+	for range 2 {
+		for k := int64(1); k <= 5; k++ {
+			request(c, k)
+		}
+	}
+	for k := int64(100); k < 200; k++ {
+		request(c, k)
+	}
+	for k := int64(1); k <= 5; k++ {
+		if !request(c, k) {
+			t.Fatalf("s3fifo: hot segment %d evicted by scan", k)
+		}
+	}
+
+	// кэш меньше фрагмента ничего не хранит
+	for _, policy := range []string{"lru", "lfu", "s3fifo"} {
+		c = cacheFor(policy, 0)
+		request(c, 1)
+		if request(c, 1) {
+			t.Fatalf("%s: zero-capacity cache hit", policy)
+		}
+	}
+}
+
+func TestOriginLinkShared(t *testing.T) {
+	sim := simgo.NewSimulation()
+	o := &originLink{mbps: 1}
+
+	var ends []float64
+	for range 2 {
+		sim.Process(func(proc simgo.Process) {
+			o.fetch(proc, 1_000_000, nil)
+			ends = append(ends, proc.Now())
+		})
+	}
+	// третья загрузка обрывается отказом сервера и освобождает канал
+	fail := sim.Event()
+	sim.Process(func(proc simgo.Process) {
+		if o.fetch(proc, 1_000_000, fail) {
+			t.Errorf("fetch finished despite server failure")
+		}
+	})
+	sim.Process(func(proc simgo.Process) {
+		proc.Wait(proc.Timeout(0.3))
+		fail.Trigger()
+	})
+	sim.Run()
+	sim.Shutdown()
+
+	// до 0.3 с канал делят три загрузки (по 0.1 Мбит), остаток 0.9 Мбит — две по 0.5 Мбит/с
+	if len(ends) != 2 || math.Abs(ends[0]-2.1) > 1e-9 || math.Abs(ends[1]-2.1) > 1e-9 {
+		t.Fatalf("ends = %v, want both 2.1", ends)
+	}
+	if len(o.active) != 0 {
+		t.Fatalf("fetches left = %d, want 0", len(o.active))
+	}
+}
@@ -0,0 +1,56 @@
+package model
+
+// Degrade ухудшает работающий сервер: пропускная способность умножается на bandwidth,
+// OWD (CurrentOWD) — на latency. Одновременные деградации перемножаются; передачи в режиме ps
+// пересчитываются по прежней скорости
+func (s *Server) Degrade(bandwidth, latency float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ps.sim != nil {
+		s.psAdvance(s.ps.sim.Now())
+	}
+	if s.degraded == 0 {
+		s.bandwidthFactor, s.latencyFactor = 1, 1
+	}
+	s.degraded++
+	s.bandwidthFactor *= bandwidth
+	s.latencyFactor *= latency
+	s.CurrentOWD *= latency
+	if s.ps.sim != nil {
+		s.psSchedule(s.ps.sim)
+	}
+}
+
+// Restore снимает деградацию, заданную Degrade с теми же множителями
+func (s *Server) Restore(bandwidth, latency float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.degraded == 0 {
+		return
+	}
+	if s.ps.sim != nil {
+		s.psAdvance(s.ps.sim.Now())
+	}
+	s.degraded--
+	s.bandwidthFactor /= bandwidth
+	s.latencyFactor /= latency
+	s.CurrentOWD /= latency
+	if s.ps.sim != nil {
+		s.psSchedule(s.ps.sim)
+	}
+}
+
+// SetOWD выставляет CurrentOWD по OWD без деградации; вызывается под Lock
+func (s *Server) SetOWD(owd float64) {
+	if s.degraded > 0 {
+		owd *= s.latencyFactor
+	}
+	s.CurrentOWD = owd
+}
+
+// IsDegraded — на сервере действует деградация
+func (s *Server) IsDegraded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.degraded > 0
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"slices"
+
+	"github.com/emrzvv/lb-research/internal/config"
+)
+
+// assignDomains записывает серверам домены отказа, в которые они входят (в порядке domains)
+func assignDomains(cfg *config.Config, servers []*Server) {
+	for _, d := range cfg.Domains {
+		for _, s := range servers {
+			in := slices.Contains(d.Servers, s.ID) || (d.PoP != "" && s.PoP == d.PoP)
+			for _, label := range d.Labels {
+				in = in || slices.Contains(s.Labels, label)
+			}
+			if in {
+				s.Domains = append(s.Domains, d.Name)
+			}
+		}
+	}
+}
+
+// InDomain — серверы домена отказа name
+func InDomain(servers []*Server, name string) []*Server {
+	var group []*Server
+	for _, s := range servers {
+		if slices.Contains(s.Domains, name) {
+			group = append(group, s)
+		}
+	}
+	return group
+}
+
+// Spike задаёт сетевой всплеск OWD сервера на +extra мс до момента until
+func (s *Server) Spike(until, extra float64) {
+	s.mu.Lock()
+	s.SpikeUntil = max(s.SpikeUntil, until)
+	s.SpikeExtra = extra
+	s.SetOWD(s.Parameters.OWD + s.Parameters.OWDExtra + extra)
+	s.mu.Unlock()
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+)
+
+func TestAssignDomains(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cfg.yaml")
+	yaml := `
+cluster:
+  servers: 6
+  pools:
+    - {name: old, count: 2, cap_mean_mbps: 300, labels: [10G], pop: a}
+    - {name: new, count: 4, cap_mean_mbps: 1200, labels: [40G], pop: b}
+pops:
+  - {name: a, servers: 2}
+  - {name: b, servers: 4}
+domains:
+  - {name: rack, servers: [2, 3]}
+  - {name: uplink, labels: [10G]}
+  - {name: site, pop: b}
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := InitServers(cfg, common.NewRNG(1))
+
+	want := [][]string{
+		{"uplink"},
+		{"rack", "uplink"},
+		{"rack", "site"},
+		{"site"},
+		{"site"},
+		{"site"},
+	}
+	for i, s := range servers {
+		if !slices.Equal(s.Domains, want[i]) {
+			t.Errorf("server %d: domains = %v, want %v", s.ID, s.Domains, want[i])
+		}
+	}
+	if got := InDomain(servers, "rack"); len(got) != 2 || got[0].ID != 2 || got[1].ID != 3 {
+		t.Errorf("rack = %v, want servers 2, 3", got)
+	}
+}
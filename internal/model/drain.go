@@ -0,0 +1,66 @@
+package model
+
+import (
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+// Drain выводит сервер из балансировки: новые сессии на него не попадают,
+// подключённые обслуживаются до окончания или до deadline (0 — без срока),
+// после чего переносятся на другие серверы. Возвращаемое событие срабатывает,
+// когда на сервере не осталось сессий и передач (nil — сервер выключен)
+func (s *Server) Drain(proc simgo.Process, deadline float64, st *stats.Statistics) *simgo.Event {
+	s.mu.Lock()
+	if s.State == StateDown || s.State == StateOff {
+		s.mu.Unlock()
+		return nil
+	}
+	s.DrainDeadline = deadline
+	if s.State == StateDraining {
+		ev := s.drained
+		s.mu.Unlock()
+		return ev
+	}
+	s.State = StateDraining
+	s.drained = proc.Event()
+	ev := s.drained
+	s.checkDrained()
+	s.mu.Unlock()
+
+	st.AddState(&stats.StateEvent{T: proc.Now(), ServerID: s.ID, State: StateDraining.String()})
+	return ev
+}
+
+// IsDraining — сервер выводится из балансировки
+func (s *Server) IsDraining() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.State == StateDraining
+}
+
+// MustMigrate — сессия должна перейти с выводимого сервера перед следующим
+// фрагментом: она ещё не подключена к нему или истёк срок вывода
+func (s *Server) MustMigrate(session *Session, now float64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.State != StateDraining {
+		return false
+	}
+	return session.server != s || (s.DrainDeadline > 0 && now >= s.DrainDeadline)
+}
+
+// checkDrained завершает вывод, если сервер опустел; вызывается под s.mu
+func (s *Server) checkDrained() {
+	if s.State == StateDraining && s.CurrentSessions == 0 && s.CurrentConnections == 0 && len(s.queue) == 0 {
+		s.releaseDrain()
+	}
+}
+
+// releaseDrain завершает вывод сервера; вызывается под s.mu
+func (s *Server) releaseDrain() {
+	if s.drained != nil {
+		s.drained.Trigger()
+		s.drained = nil
+	}
+	s.DrainDeadline = 0
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"slices"
+
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+type ServerState int
+
+const (
+	StateActive ServerState = iota
+	StateDraining
+	StateDown
+	StateOff // резерв автомасштабирования
+)
+
+func (st ServerState) String() string {
+	switch st {
+	case StateDraining:
+		return "draining"
+	case StateDown:
+		return "down"
+	case StateOff:
+		return "off"
+	}
+	return "active"
+}
+
+// IsDown — сервер выключен (отказ или резерв автомасштабирования)
+func (s *Server) IsDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.State == StateDown || s.State == StateOff
+}
+
+// IsAvailable — сервер принимает новые сессии
+func (s *Server) IsAvailable() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.State == StateActive
+}
+
+// Fail выключает сервер: запросы в обработке и в очереди завершаются отказом server_down.
+// owner — причина выключения (отказ сервера, домена, сценарий, перезапуск): сервер,
+// выключенный по нескольким причинам, возвращается в работу, когда их все снимет Recover
+func (s *Server) Fail(now float64, st *stats.Statistics, owner string) {
+	s.mu.Lock()
+	if s.State == StateOff {
+		s.mu.Unlock()
+		return
+	}
+	if !slices.Contains(s.downBy, owner) {
+		s.downBy = append(s.downBy, owner)
+	}
+	if s.State == StateDown {
+		s.mu.Unlock()
+		return
+	}
+	s.State = StateDown
+	s.abort()
+	s.mu.Unlock()
+
+	st.AddState(&stats.StateEvent{T: now, ServerID: s.ID, State: StateDown.String()})
+}
+
+// abort завершает отказом запросы в обработке и в очереди; вызывается под s.mu
+func (s *Server) abort() {
+	for _, ev := range s.inflight {
+		ev.Trigger()
+	}
+	s.inflight = nil
+	for _, w := range s.queue {
+		w.ev.Trigger()
+	}
+	s.queue = nil
+	s.ps.active = nil
+	s.ps.gen++
+	s.releaseDrain()
+}
+
+// Recover снимает причину выключения owner и возвращает сервер в работу, если других
+// причин не осталось; выводимый из балансировки сервер возвращается сразу
+func (s *Server) Recover(now float64, st *stats.Statistics, owner string) {
+	s.mu.Lock()
+	if s.State == StateActive || s.State == StateOff {
+		s.mu.Unlock()
+		return
+	}
+	s.downBy = slices.DeleteFunc(s.downBy, func(o string) bool { return o == owner })
+	if s.State == StateDown && len(s.downBy) > 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.State = StateActive
+	s.releaseDrain()
+	s.mu.Unlock()
+
+	st.AddState(&stats.StateEvent{T: now, ServerID: s.ID, State: StateActive.String()})
+}
+
+// track регистрирует запрос в обработке: событие срабатывает при отказе сервера;
+// вызывается под s.mu
+func (s *Server) track(proc simgo.Process) *simgo.Event {
+	fail := proc.Event()
+	s.inflight = append(s.inflight, fail)
+	return fail
+}
+
+// untrack снимает запрос с учёта; вызывается под s.mu
+func (s *Server) untrack(fail *simgo.Event) {
+	if i := slices.Index(s.inflight, fail); i >= 0 {
+		s.inflight = slices.Delete(s.inflight, i, i+1)
+	}
+}
+
+// wait ждёт ev или отказа сервера (fail == nil — отказы не моделируются);
+// false — сервер отказал
+func wait(proc simgo.Process, ev, fail *simgo.Event) bool {
+	if fail == nil {
+		proc.Wait(ev)
+		return true
+	}
+	proc.Wait(proc.AnyOf(ev, fail))
+	return !fail.Triggered()
+}
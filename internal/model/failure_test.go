@@ -0,0 +1,115 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+func TestFailAbortsInFlightRequests(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Cluster.Servers = 1
+	cfg.Cluster.SegmentSizeBytes = 1_000_000 // 1 с на 8 Мбит/с
+	cfg.Cluster.ServiceNoise = config.Dist{Type: "constant", Value: 1}
+	cfg.Failures.MTBF = 1
+	st := stats.NewStatistics(cfg)
+	rng := common.NewRNG(1)
+
+	sim := simgo.NewSimulation()
+	s := &Server{ID: 1, Parameters: &ServerParameters{Mbps: 8, MaxConnections: 1, QueueSize: 1}}
+	for i, at := range []float64{0, 0.1, 0.6, 1.1} {
+		sim.Process(func(proc simgo.Process) {
+			proc.Wait(proc.Timeout(at))
+			s.HandleRequest(proc, proc.Now(), 0, &Session{ID: int64(i + 1)}, cfg, st, rng)
+		})
+	}
+	sim.Process(func(proc simgo.Process) {
+		proc.Wait(proc.Timeout(0.5))
+		s.Fail(proc.Now(), st, "failure")
+		proc.Wait(proc.Timeout(0.5))
+		s.Recover(proc.Now(), st, "failure")
+	})
+	sim.Run()
+	sim.Shutdown()
+
+	// 1 — в обработке, 2 — в очереди, 3 — пришёл к выключенному серверу
+	at := map[int64]float64{}
+	for _, d := range st.Drops {
+		if d.Reason != "server_down" {
+			t.Fatalf("drop %+v, want server_down", d)
+		}
+		at[d.SessionID] = d.T
+	}
+	if at[1] != 0.5 || at[2] != 0.5 || at[3] != 0.6 {
+		t.Fatalf("drop times = %v, want 1: 0.5, 2: 0.5, 3: 0.6", at)
+	}
+	if len(st.Drops) != 3 || len(st.ServerRequests) != 1 || st.ServerRequests[0].SessiontID != 4 {
+		t.Fatalf("drops/served = %d/%d, want 3/1 (session 4)", len(st.Drops), len(st.ServerRequests))
+	}
+	if len(st.States) != 2 || st.States[0].State != "down" || st.States[1].State != "active" {
+		t.Fatalf("states = %v, want down, active", st.States)
+	}
+	if s.Load() != 0 {
+		t.Fatalf("load = %d, want 0", s.Load())
+	}
+}
+
+func TestDrainWaitsForAttachedSessions(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Cluster.Servers = 1
+	cfg.Cluster.SegmentSizeBytes = 1_000_000 // 1 с на 8 Мбит/с
+	cfg.Cluster.ServiceNoise = config.Dist{Type: "constant", Value: 1}
+	st := stats.NewStatistics(cfg)
+	rng := common.NewRNG(1)
+
+	sim := simgo.NewSimulation()
+	s := &Server{ID: 1, Parameters: &ServerParameters{Mbps: 8, MaxConnections: 2}}
+	attached, fresh := &Session{ID: 1}, &Session{ID: 2}
+	sim.Process(func(proc simgo.Process) {
+		s.HandleRequest(proc, proc.Now(), 0, attached, cfg, st, rng)
+		proc.Wait(proc.Timeout(1))
+		attached.Detach(cfg)
+	})
+
+	drainedAt := -1.0
+	sim.Process(func(proc simgo.Process) {
+		proc.Wait(proc.Timeout(0.5))
+		ev := s.Drain(proc, 0, st)
+		if s.IsAvailable() || !s.MustMigrate(fresh, proc.Now()) || s.MustMigrate(attached, proc.Now()) {
+			t.Errorf("draining server: available %v, migrate fresh/attached %v/%v",
+				s.IsAvailable(), s.MustMigrate(fresh, proc.Now()), s.MustMigrate(attached, proc.Now()))
+		}
+		proc.Wait(ev)
+		drainedAt = proc.Now()
+	})
+	sim.Run()
+	sim.Shutdown()
+
+	// сессия дослушала фрагмент (до 1 с) и отключилась в 2 с
+	if len(st.ServerRequests) != 1 || drainedAt != 2 {
+		t.Fatalf("served = %d, drained at %v, want 1, 2", len(st.ServerRequests), drainedAt)
+	}
+	if len(st.States) != 1 || st.States[0].State != "draining" {
+		t.Fatalf("states = %v, want draining", st.States)
+	}
+}
+
+func TestPowerOffClearsDownOwners(t *testing.T) {
+	cfg := &config.Config{}
+	st := stats.NewStatistics(cfg)
+	s := &Server{ID: 1, Parameters: &ServerParameters{Mbps: 8, MaxConnections: 1}}
+
+	// сервер отказал во время вывода в резерв, ремонт завершился уже в резерве
+	s.Fail(1, st, "failure")
+	s.PowerOff(2, st)
+	s.Recover(3, st, "failure")
+	s.PowerOn(4, st)
+	s.Fail(5, st, "scenario")
+	s.Recover(6, st, "scenario")
+	if !s.IsAvailable() {
+		t.Fatalf("state = %v, want active after the scenario recovers it", s.State)
+	}
+}
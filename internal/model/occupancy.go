@@ -0,0 +1,70 @@
+package model
+
+import (
+	"github.com/emrzvv/lb-research/internal/config"
+)
+
+// used — занятые соединения: подключённые сессии при cluster.sessions.mode: slot,
+// иначе активные передачи; вызывается под s.mu
+func (s *Server) used() int {
+	if s.Parameters.SessionSlots {
+		return s.CurrentSessions
+	}
+	return s.CurrentConnections
+}
+
+// full — нет места для запроса сессии: при slot уже подключённая сессия
+// пользуется своим соединением; вызывается под s.mu
+func (s *Server) full(session *Session) bool {
+	if s.Parameters.SessionSlots && session.server == s {
+		return false
+	}
+	return s.used() >= s.Parameters.MaxConnections
+}
+
+// mbps — пропускная способность для передач с учётом деградации, за вычетом резерва
+// подключённых сессий (не меньше 1% при переподписке); вызывается под s.mu
+func (s *Server) mbps() float64 {
+	mbps := s.Parameters.Mbps
+	if s.degraded > 0 {
+		mbps *= s.bandwidthFactor
+	}
+	reserved := s.Parameters.ReserveMbps * float64(s.CurrentSessions)
+	return max(mbps-reserved, 0.01*mbps)
+}
+
+// attach подключает сессию к серверу; вызывается под s.mu
+func (s *Server) attach(session *Session) {
+	if session.server == s {
+		return
+	}
+	s.setSessions(s.CurrentSessions + 1)
+	session.server = s
+}
+
+// setSessions меняет число подключённых сессий; при резерве полосы
+// остатки передач пересчитываются по прежней скорости; вызывается под s.mu
+func (s *Server) setSessions(n int) {
+	if s.Parameters.ReserveMbps > 0 && s.ps.sim != nil {
+		s.psAdvance(s.ps.sim.Now())
+		s.CurrentSessions = n
+		s.psSchedule(s.ps.sim)
+		return
+	}
+	s.CurrentSessions = n
+}
+
+// Detach отключает сессию от сервера (окончание сессии или переброс)
+// и отдаёт освободившееся соединение очереди
+func (session *Session) Detach(cfg *config.Config) {
+	s := session.server
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.setSessions(s.CurrentSessions - 1)
+	session.server = nil
+	s.dequeue(cfg.Cluster.Queue.Discipline)
+	s.checkDrained()
+	s.mu.Unlock()
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/fschuetz04/simgo"
+)
+
+// originLink — канал от серверов с кэшем к origin (cluster.cache.origin_mbps), общий
+// для всех серверов: одновременные загрузки при промахах делят его поровну
+// (processor sharing, как передачи сервера в ps.go)
+type originLink struct {
+	mu     sync.Mutex
+	mbps   float64
+	active []*transfer
+	last   float64 // время последнего пересчёта остатков
+	gen    int
+}
+
+// fetch загружает bits бит с origin и ждёт окончания загрузки; false — сервер
+// отказал раньше, тогда загрузка снимается с канала
+func (o *originLink) fetch(proc simgo.Process, bits float64, fail *simgo.Event) bool {
+	t := &transfer{remaining: bits, done: proc.Event()}
+	o.mu.Lock()
+	o.advance(proc.Now())
+	o.active = append(o.active, t)
+	o.schedule(proc.Simulation)
+	o.mu.Unlock()
+	if wait(proc, t.done, fail) {
+		return true
+	}
+	o.mu.Lock()
+	if i := slices.Index(o.active, t); i >= 0 {
+		o.advance(proc.Now())
+		o.active = slices.Delete(o.active, i, i+1)
+		o.schedule(proc.Simulation)
+	}
+	o.mu.Unlock()
+	return false
+}
+
+// rate — скорость одной загрузки, бит/с
+func (o *originLink) rate() float64 {
+	return o.mbps * 1_000_000 / float64(len(o.active))
+}
+
+func (o *originLink) advance(now float64) {
+	if len(o.active) > 0 {
+		sent := (now - o.last) * o.rate()
+		for _, t := range o.active {
+			t.remaining -= sent
+		}
+	}
+	o.last = now
+}
+
+func (o *originLink) schedule(sim *simgo.Simulation) {
+	o.gen++
+	if len(o.active) == 0 {
+		return
+	}
+	next := o.active[0].remaining
+	for _, t := range o.active[1:] {
+		next = min(next, t.remaining)
+	}
+
+	gen := o.gen
+	sim.Timeout(max(next, 0) / o.rate()).AddHandler(func(*simgo.Event) {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if gen != o.gen {
+			return
+		}
+		o.advance(sim.Now())
+		active := o.active[:0]
+		for _, t := range o.active {
+			if t.remaining <= psEpsilon {
+				t.done.Trigger()
+				continue
+			}
+			active = append(active, t)
+		}
+		clear(o.active[len(active):])
+		o.active = active
+		o.schedule(sim)
+	})
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"github.com/fschuetz04/simgo"
+)
+
+// psEpsilon — остаток передачи (бит), при котором она считается завершённой
+const psEpsilon = 1.0
+
+type transfer struct {
+	remaining float64 // бит осталось передать
+	done      *simgo.Event
+}
+
+// psState — активные передачи сервера, поровну делящие его пропускную способность
+// (processor sharing). При каждом входе/выходе передачи остатки пересчитываются
+// и планируется ближайшее завершение; ранее запланированные завершения
+// отбрасываются по номеру поколения gen.
+type psState struct {
+	active []*transfer
+	last   float64 // время последнего пересчёта остатков
+	gen    int
+	sim    *simgo.Simulation
+}
+
+// share передаёт bits бит в режиме processor sharing и ждёт окончания передачи;
+// false — сервер отказал до окончания
+func (s *Server) share(proc simgo.Process, bits float64, fail *simgo.Event) bool {
+	t := &transfer{remaining: bits, done: proc.Event()}
+	s.mu.Lock()
+	s.ps.sim = proc.Simulation
+	s.psAdvance(proc.Now())
+	s.ps.active = append(s.ps.active, t)
+	s.psSchedule(proc.Simulation)
+	s.mu.Unlock()
+	return wait(proc, t.done, fail)
+}
+
+// Transfers — кол-во передач, делящих пропускную способность сервера
+func (s *Server) Transfers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.ps.active)
+}
+
+// psRate — скорость одной передачи, бит/с
+func (s *Server) psRate() float64 {
+	return s.mbps() * 1_000_000 / float64(len(s.ps.active))
+}
+
+func (s *Server) psAdvance(now float64) {
+	if len(s.ps.active) > 0 {
+		sent := (now - s.ps.last) * s.psRate()
+		for _, t := range s.ps.active {
+			t.remaining -= sent
+		}
+	}
+	s.ps.last = now
+}
+
+func (s *Server) psSchedule(sim *simgo.Simulation) {
+	s.ps.gen++
+	if len(s.ps.active) == 0 {
+		return
+	}
+	next := s.ps.active[0].remaining
+	for _, t := range s.ps.active[1:] {
+		next = min(next, t.remaining)
+	}
+
+	gen := s.ps.gen
+	sim.Timeout(max(next, 0) / s.psRate()).AddHandler(func(*simgo.Event) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if gen != s.ps.gen {
+			return
+		}
+		s.psAdvance(sim.Now())
+		active := s.ps.active[:0]
+		for _, t := range s.ps.active {
+			if t.remaining <= psEpsilon {
+				t.done.Trigger()
+				continue
+			}
+			active = append(active, t)
+		}
+		clear(s.ps.active[len(active):])
+		s.ps.active = active
+		s.psSchedule(sim)
+	})
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fschuetz04/simgo"
+)
+
+func TestProcessorSharing(t *testing.T) {
+	sim := simgo.NewSimulation()
+	s := &Server{ID: 1, Parameters: &ServerParameters{Mbps: 1}}
+
+	var endA, endB float64
+	sim.Process(func(proc simgo.Process) {
+		s.share(proc, 1_000_000, nil)
+		endA = proc.Now()
+	})
+	sim.Process(func(proc simgo.Process) {
+		proc.Wait(proc.Timeout(0.5))
+		s.share(proc, 250_000, nil)
+		endB = proc.Now()
+	})
+	sim.Run()
+	sim.Shutdown()
+
+	// A один передаёт 0.5 Мбит за 0.5 с, затем A и B делят 1 Мбит/с:
+	// B завершается в 1.0, остаток A (0.25 Мбит) уходит на полной скорости к 1.25
+	if math.Abs(endB-1.0) > 1e-9 || math.Abs(endA-1.25) > 1e-9 {
+		t.Fatalf("end A, B = %v, %v, want 1.25, 1.0", endA, endB)
+	}
+	if n := s.Transfers(); n != 0 {
+		t.Fatalf("transfers left = %d, want 0", n)
+	}
+}
+
+func TestDegradeSlowsTransfer(t *testing.T) {
+	sim := simgo.NewSimulation()
+	s := &Server{ID: 1, Parameters: &ServerParameters{Mbps: 1}}
+
+	var end float64
+	sim.Process(func(proc simgo.Process) {
+		s.share(proc, 1_000_000, nil)
+		end = proc.Now()
+	})
+	sim.Process(func(proc simgo.Process) {
+		proc.Wait(proc.Timeout(0.5))
+		s.Degrade(0.5, 2)
+		proc.Wait(proc.Timeout(0.5))
+		s.Restore(0.5, 2)
+	})
+	sim.Run()
+	sim.Shutdown()
+
+	// 0.5 Мбит за 0.5 с, 0.25 Мбит за 0.5 с на половинной скорости, остаток — за 0.25 с
+	if math.Abs(end-1.25) > 1e-9 {
+		t.Fatalf("end = %v, want 1.25", end)
+	}
+	if s.IsDegraded() {
+		t.Fatalf("server is still degraded after restore")
+	}
+}
+
+func TestDegradeScalesOWD(t *testing.T) {
+	s := &Server{ID: 1, CurrentOWD: 10, Parameters: &ServerParameters{Mbps: 1}}
+	s.Degrade(1, 2)
+	if s.CurrentOWD != 20 {
+		t.Fatalf("degraded owd = %v, want 20", s.CurrentOWD)
+	}
+	// новое значение джиттера тоже проходит через множитель деградации
+	s.Lock()
+	s.SetOWD(12)
+	s.Unlock()
+	s.SetOWDExtra(3)
+	if s.CurrentOWD != 30 {
+		t.Fatalf("degraded owd after jitter and extra = %v, want 30", s.CurrentOWD)
+	}
+	s.Restore(1, 2)
+	if s.CurrentOWD != 15 {
+		t.Fatalf("restored owd = %v, want 15", s.CurrentOWD)
+	}
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"github.com/fschuetz04/simgo"
+)
+
+// waiter — запрос, ожидающий свободного соединения в очереди сервера
+type waiter struct {
+	ev      *simgo.Event
+	session *Session // по номеру сегмента: дольше смотрящие обслуживаются раньше (priority)
+	granted bool     // соединение уже выделено освободившим его запросом
+}
+
+// enqueue ставит запрос в очередь; вызывается под s.mu
+func (s *Server) enqueue(ev *simgo.Event, session *Session) *waiter {
+	w := &waiter{ev: ev, session: session}
+	s.queue = append(s.queue, w)
+	return w
+}
+
+// remove убирает запрос из очереди (по таймауту); вызывается под s.mu
+func (s *Server) remove(w *waiter) {
+	for i, q := range s.queue {
+		if q == w {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// dequeue передаёт освободившиеся соединения запросам очереди
+// по дисциплине cluster.queue.discipline; вызывается под s.mu
+func (s *Server) dequeue(discipline string) {
+	for len(s.queue) > 0 && s.grant(discipline) {
+	}
+}
+
+func (s *Server) grant(discipline string) bool {
+	i := 0
+	switch discipline {
+	case "lifo":
+		i = len(s.queue) - 1
+	case "priority":
+		for j, w := range s.queue {
+			if w.session.Segment > s.queue[i].session.Segment {
+				i = j
+			}
+		}
+	}
+	w := s.queue[i]
+	if s.full(w.session) {
+		return false
+	}
+	s.queue = append(s.queue[:i], s.queue[i+1:]...)
+	w.granted = true
+	s.CurrentConnections++
+	s.attach(w.session)
+	w.ev.Trigger()
+	return true
+}
+
+// QueueLen — кол-во запросов в очереди сервера
+func (s *Server) QueueLen() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// Load — активные соединения вместе с очередью
+func (s *Server) Load() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.used() + len(s.queue)
+}
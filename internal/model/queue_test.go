@@ -0,0 +1,51 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+func TestQueueLIFOWithTimeout(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Cluster.Servers = 1
+	cfg.Cluster.SegmentSizeBytes = 1_000_000 // 1 с на 8 Мбит/с
+	cfg.Cluster.ServiceNoise = config.Dist{Type: "constant", Value: 1}
+	cfg.Cluster.Queue.Discipline = "lifo"
+	cfg.Cluster.Queue.Timeout = 1.5
+	st := stats.NewStatistics(cfg)
+	rng := common.NewRNG(1)
+
+	sim := simgo.NewSimulation()
+	s := &Server{ID: 1, Parameters: &ServerParameters{Mbps: 8, MaxConnections: 1, QueueSize: 2}}
+	for i, at := range []float64{0, 0.1, 0.2, 0.3} {
+		sim.Process(func(proc simgo.Process) {
+			proc.Wait(proc.Timeout(at))
+			s.HandleRequest(proc, proc.Now(), 0, &Session{ID: int64(i + 1)}, cfg, st, rng)
+		})
+	}
+	sim.Run()
+	sim.Shutdown()
+
+	// 1 обслуживается сразу, 2 и 3 ждут, 4 не помещается в очередь;
+	// в 1.0 соединение получает последний пришедший (3), 2 уходит по таймауту в 1.6
+	drops := map[int64]string{}
+	for _, d := range st.Drops {
+		drops[d.SessionID] = d.Reason
+	}
+	if drops[2] != "queue_timeout" || drops[4] != "queue_full" || len(drops) != 2 {
+		t.Fatalf("drops = %v, want 2: queue_timeout, 4: queue_full", drops)
+	}
+	if len(st.ServerRequests) != 2 {
+		t.Fatalf("served = %d, want 2", len(st.ServerRequests))
+	}
+	if r := st.ServerRequests[1]; r.SessiontID != 3 || r.T2 != 2 {
+		t.Fatalf("second request = session %d ending at %v, want session 3 at 2", r.SessiontID, r.T2)
+	}
+	if s.Load() != 0 {
+		t.Fatalf("load = %d, want 0", s.Load())
+	}
+}
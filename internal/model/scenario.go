@@ -0,0 +1,34 @@
+package model
+
+import (
+	"github.com/emrzvv/lb-research/internal/config"
+)
+
+// SetCapacity меняет пропускную способность и лимит соединений сервера;
+// передачи в режиме ps пересчитываются по прежней скорости, очередь получает
+// освободившиеся соединения
+func (s *Server) SetCapacity(mbps float64, maxConnections int, cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ps.sim != nil {
+		s.psAdvance(s.ps.sim.Now())
+	}
+	s.Parameters.Mbps = mbps
+	s.Parameters.MaxConnections = maxConnections
+	if s.ps.sim != nil {
+		s.psSchedule(s.ps.sim)
+	}
+	s.dequeue(cfg.Cluster.Queue.Discipline)
+}
+
+// SetOWDExtra задаёт добавку к OWD сервера, мс
+func (s *Server) SetOWDExtra(extra float64) {
+	s.mu.Lock()
+	delta := extra - s.Parameters.OWDExtra
+	if s.degraded > 0 {
+		delta *= s.latencyFactor
+	}
+	s.CurrentOWD += delta
+	s.Parameters.OWDExtra = extra
+	s.mu.Unlock()
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/emrzvv/lb-research/internal/common"
 	"github.com/emrzvv/lb-research/internal/config"
-	"github.com/emrzvv/lb-research/internal/metric"
 	"github.com/emrzvv/lb-research/internal/stats"
 	"github.com/fschuetz04/simgo"
 )
@@ -15,35 +14,66 @@ type ServerParameters struct {
 	Mbps           float64
 	OWD            float64
 	MaxConnections int
+	OWDDist        *config.Dist // распределение OWD для джиттера
+	OWDExtra       float64      // добавка к OWD (scenario: owd), мс
+	QueueSize      int          // макс. длина очереди (0 — без очереди)
+	SessionSlots   bool         // подключённая сессия занимает соединение всё время жизни
+	ReserveMbps    float64      // резерв пропускной способности на подключённую сессию
 }
 
 type ServerSnapshot struct {
 	T           float64
-	Connections int
+	Connections int // активные передачи
+	Sessions    int // подключённые сессии
+	Queue       int
 	OWD         float64
 }
 
-func NewSnapshot(t float64, connections int, owd float64) *ServerSnapshot {
+func NewSnapshot(t float64, connections, sessions, queue int, owd float64) *ServerSnapshot {
 	return &ServerSnapshot{
 		T:           t,
 		Connections: connections,
+		Sessions:    sessions,
+		Queue:       queue,
 		OWD:         owd,
 	}
 }
 
 type Server struct {
 	ID                 int
+	PoP                string   // точка присутствия (пусто — плоский кластер)
+	Region             string   // регион сервера из geo.regions (пусто — география не задана)
+	Pool               string   // пул оборудования из cluster.pools (пусто — пулы не заданы)
+	Labels             []string // метки пула
+	Domains            []string // домены отказа из domains
 	CurrentConnections int
-	CurrentOWD         float64
+	CurrentSessions    int     // сессии, подключённые к серверу (keep-alive)
+	CurrentOWD         float64 // с учётом деградации (SetOWD)
 	SpikeUntil         float64
+	SpikeExtra         float64 // +мс к OWD во время всплеска
+	State              ServerState
+	downBy             []string // причины выключения (Fail), пока сервер в StateDown
 	Parameters         *ServerParameters
 	Snapshots          []*ServerSnapshot
+	ps                 psState        // передачи при cluster.bandwidth_model: ps
+	queue              []*waiter      // очередь запросов при занятых соединениях
+	inflight           []*simgo.Event // запросы в обработке (срабатывают при отказе)
+	DrainDeadline      float64        // после этого момента подключённые сессии переносятся (0 — без срока)
+	drained            *simgo.Event   // срабатывает, когда у выводимого сервера не осталось сессий
+	degraded           int            // число действующих деградаций
+	bandwidthFactor    float64        // множитель пропускной способности при деградации
+	latencyFactor      float64        // множитель OWD при деградации
+	cache              segmentCache   // кэш фрагментов (nil — cluster.cache не задан)
+	origin             *originLink    // канал к origin, общий для серверов с кэшем
+	CacheHits          int
+	CacheMisses        int
+	OriginBytes        float64 // загружено с origin при промахах, байт
 	mu                 sync.Mutex
 }
 
 func (s *Server) AddSnapshot(t float64) {
 	s.mu.Lock()
-	ss := NewSnapshot(t, s.CurrentConnections, s.CurrentOWD)
+	ss := NewSnapshot(t, s.CurrentConnections, s.CurrentSessions, len(s.queue), s.CurrentOWD)
 	s.Snapshots = append(s.Snapshots, ss)
 	s.mu.Unlock()
 }
@@ -58,66 +88,150 @@ func (s *Server) Unlock() {
 
 func (s *Server) IsOverLoaded() bool {
 	s.mu.Lock()
-	result := s.CurrentConnections >= s.Parameters.MaxConnections
+	result := s.used() >= s.Parameters.MaxConnections && len(s.queue) >= s.Parameters.QueueSize
 	s.mu.Unlock()
 	return result
 }
 
 func (s *Server) HandleRequest(
-	session simgo.Process,
+	proc simgo.Process,
 	start float64,
 	penalty float64,
-	sessionID int64,
+	session *Session,
 	cfg *config.Config,
 	st *stats.Statistics,
 	rng *common.RNG) bool {
 
 	s.Lock()
-	if s.CurrentConnections >= s.Parameters.MaxConnections {
+	if s.State == StateDown || s.State == StateOff {
 		s.Unlock()
 		st.AddDrop(&stats.DropEvent{
 			ServerID:  s.ID,
-			SessionID: sessionID,
+			SessionID: session.ID,
 			T:         start,
-			Reason:    "max_conn",
+			Reason:    "server_down",
 		})
 		return false
 	}
+	queued := 0.0
+	if s.full(session) {
+		reason := "max_conn"
+		if s.Parameters.QueueSize > 0 {
+			reason = "queue_full"
+		}
+		if len(s.queue) >= s.Parameters.QueueSize {
+			s.Unlock()
+			st.AddDrop(&stats.DropEvent{
+				ServerID:  s.ID,
+				SessionID: session.ID,
+				T:         start,
+				Reason:    reason,
+			})
+			return false
+		}
 
-	s.CurrentConnections++
+		w := s.enqueue(proc.Event(), session)
+		s.Unlock()
+		if timeout := cfg.Cluster.Queue.Timeout; timeout > 0 {
+			proc.Wait(proc.AnyOf(w.ev, proc.Timeout(timeout)))
+		} else {
+			proc.Wait(w.ev)
+		}
+		s.Lock()
+		if !w.granted {
+			reason := "queue_timeout"
+			if s.State == StateDown || s.State == StateOff {
+				reason = "server_down"
+			}
+			s.remove(w)
+			s.Unlock()
+			st.AddDrop(&stats.DropEvent{
+				ServerID:  s.ID,
+				SessionID: session.ID,
+				T:         proc.Now(),
+				Reason:    reason,
+			})
+			return false
+		}
+		queued = proc.Now() - start
+	} else {
+		s.CurrentConnections++
+		s.attach(session)
+	}
+	var fail *simgo.Event
+	if cfg.ServerFailures() {
+		fail = s.track(proc)
+	}
+	miss := s.lookup(cfg, session)
 	s.Unlock()
 
-	duration := s.getDuration(cfg, rng) + penalty
-	session.Wait(session.Timeout(duration))
+	// при промахе фрагмент сначала загружается с origin по общему каналу
+	ok := true
+	var origin float64
+	if miss {
+		ok = wait(proc, proc.Timeout(cfg.Cluster.Cache.OriginRTT/1000.0), fail) &&
+			s.origin.fetch(proc, cfg.Cluster.SegmentSizeBytes*8, fail)
+		origin = proc.Now() - start - queued
+	}
+
+	var duration float64
+	if ok && cfg.Cluster.BandwidthModel == "ps" {
+		ok = wait(proc, proc.Timeout(penalty+2*s.OWDFor(session, cfg)/1000.0), fail) &&
+			s.share(proc, cfg.Cluster.SegmentSizeBytes*8*cfg.Cluster.ServiceNoise.Sample(rng), fail)
+		duration = proc.Now() - start
+	} else if ok {
+		d := s.getDuration(cfg, rng, session) + penalty
+		ok = wait(proc, proc.Timeout(d), fail)
+		duration = queued + origin + d
+	}
 	s.Lock()
 	s.CurrentConnections--
+	if fail != nil {
+		s.untrack(fail)
+	}
+	s.dequeue(cfg.Cluster.Queue.Discipline)
+	s.checkDrained()
 	s.Unlock()
 
+	if !ok {
+		st.AddDrop(&stats.DropEvent{
+			ServerID:  s.ID,
+			SessionID: session.ID,
+			T:         proc.Now(),
+			Reason:    "server_down",
+		})
+		return false
+	}
+
 	st.AddRequest(&stats.RequestEvent{
 		ServerID:   s.ID,
-		SessiontID: sessionID,
+		SessiontID: session.ID,
+		ContentID:  session.ContentID,
 		T1:         start,
 		T2:         start + duration,
 		Duration:   duration,
+		Queued:     queued,
+		Service:    duration - queued - penalty,
 	})
-	if ch := metric.RTTChan; ch != nil {
-		select {
-		case ch <- metric.RequestRTT{
-			ServerID: s.ID,
-			RTT:      duration,
-			When:     start,
-		}:
-		default:
-		}
-	}
-
 	return true
 }
 
-func (s *Server) getDuration(cfg *config.Config, rng *common.RNG) float64 {
-	txMean := cfg.Cluster.SegmentSizeBytes * 8 / (s.Parameters.Mbps * 1_000_000)
-	lnDist := RandLogNormal(math.Log(txMean), cfg.Cluster.SigmaServer, rng)
-	rtt := lnDist + 2*s.CurrentOWD/1000.0 // to seconds
+// OWDFor — one-way delay между клиентом сессии и сервером, мс
+func (s *Server) OWDFor(session *Session, cfg *config.Config) float64 {
+	s.mu.Lock()
+	owd := s.CurrentOWD
+	s.mu.Unlock()
+	return owd + cfg.ClientLatency(session.Region, s.ID, s.Region)
+}
+
+// getDuration — время запроса фрагмента без загрузки с origin, сек
+func (s *Server) getDuration(cfg *config.Config, rng *common.RNG, session *Session) float64 {
+	s.mu.Lock()
+	mbps := s.mbps()
+	s.mu.Unlock()
+	txMean := cfg.Cluster.SegmentSizeBytes * 8 / (mbps * 1_000_000)
+	tx := txMean * cfg.Cluster.ServiceNoise.Sample(rng)
+	rtt := tx + 2*s.OWDFor(session, cfg)/1000.0 // to seconds
 	return rtt
 }
 
@@ -128,15 +242,47 @@ type Spike struct {
 }
 
 func InitServers(cfg *config.Config, rng *common.RNG) []*Server {
+	servers := newServers(cfg, rng)
+	assignDomains(cfg, servers)
+	if cfg.Cluster.Cache.Bytes > 0 {
+		origin := &originLink{mbps: cfg.Cluster.Cache.OriginMbps}
+		for _, s := range servers {
+			s.cache = newSegmentCache(cfg)
+			s.origin = origin
+		}
+	}
+	return servers
+}
+
+func newServers(cfg *config.Config, rng *common.RNG) []*Server {
+	if len(cfg.Topology.Servers) > 0 {
+		return loadServers(cfg)
+	}
+	if len(cfg.Cluster.Pools) > 0 {
+		return poolServers(cfg, rng)
+	}
+
+	var pops, regions []string
+	for _, p := range cfg.PoPs {
+		for range p.Servers {
+			pops = append(pops, p.Name)
+			regions = append(regions, p.Region)
+		}
+	}
+
 	var servers []*Server
-	for i := range cfg.Cluster.Servers {
-		mbps := RandNormal(cfg.Cluster.CapMean, cfg.Cluster.CapCV, rng)
-		owd := RandGamma(cfg.Cluster.OWDMean, cfg.Cluster.OWDCV, rng)
+	for i := range cfg.TotalServers() {
+		mbps := cfg.Cluster.Capacity.Sample(rng)
+		owd := cfg.Cluster.OWD.Sample(rng)
 
 		p := &ServerParameters{
 			Mbps:           mbps,
 			OWD:            owd,
 			MaxConnections: int(math.Floor(mbps / float64(cfg.Cluster.Bitrate))),
+			OWDDist:        &cfg.Cluster.OWD,
+			QueueSize:      cfg.Cluster.Queue.Size,
+			SessionSlots:   cfg.Cluster.Sessions.Mode == "slot",
+			ReserveMbps:    cfg.Cluster.Sessions.Reserve,
 		}
 
 		s := &Server{
@@ -148,8 +294,103 @@ func InitServers(cfg *config.Config, rng *common.RNG) []*Server {
 			mu:                 sync.Mutex{},
 		}
 
+		if i >= cfg.Cluster.Servers {
+			s.State = StateOff // резерв автомасштабирования
+		}
+		if i < len(pops) {
+			s.PoP = pops[i]
+			s.Region = regions[i]
+		} else if n := len(cfg.Geo.Regions); n > 0 {
+			s.Region = cfg.Geo.Regions[i%n].Name
+		}
+
 		servers = append(servers, s)
 	}
 
 	return servers
 }
+
+// loadServers создаёт серверы из topology.servers_file
+func loadServers(cfg *config.Config) []*Server {
+	servers := make([]*Server, len(cfg.Topology.Servers))
+	for _, spec := range cfg.Topology.Servers {
+		maxConn := spec.MaxConnections
+		if maxConn == 0 {
+			maxConn = int(math.Floor(spec.Mbps / cfg.Cluster.Bitrate))
+		}
+		p := &ServerParameters{
+			Mbps:           spec.Mbps,
+			OWD:            spec.OWD,
+			MaxConnections: maxConn,
+			OWDDist:        specOWD(spec),
+			QueueSize:      cfg.Cluster.Queue.Size,
+			SessionSlots:   cfg.Cluster.Sessions.Mode == "slot",
+			ReserveMbps:    cfg.Cluster.Sessions.Reserve,
+		}
+		servers[spec.ID-1] = &Server{
+			ID:         spec.ID,
+			PoP:        spec.PoP,
+			Region:     spec.Region,
+			CurrentOWD: p.OWD,
+			Parameters: p,
+			Snapshots:  make([]*ServerSnapshot, 0),
+			mu:         sync.Mutex{},
+		}
+	}
+	return servers
+}
+
+// specOWD — джиттер сервера из файла топологии вокруг измеренной owd_ms:
+// гамма с СКО owd_jitter_ms (неотрицательна), без owd_jitter_ms — постоянная owd_ms
+func specOWD(spec config.ServerSpec) *config.Dist {
+	if spec.Jitter == 0 || spec.OWD == 0 {
+		return &config.Dist{Type: "constant", Value: spec.OWD}
+	}
+	return &config.Dist{Type: "gamma", Mean: spec.OWD, CV: spec.Jitter / spec.OWD}
+}
+
+// poolServers создаёт серверы по cluster.pools: ID идут подряд в порядке пулов.
+// Пропускная способность ограничивается [min_mbps, max_mbps] пула
+func poolServers(cfg *config.Config, rng *common.RNG) []*Server {
+	regions := make(map[string]string, len(cfg.PoPs))
+	for _, p := range cfg.PoPs {
+		regions[p.Name] = p.Region
+	}
+
+	servers := make([]*Server, 0, cfg.Cluster.Servers)
+	for i := range cfg.Cluster.Pools {
+		pool := &cfg.Cluster.Pools[i]
+		for range pool.Count {
+			mbps := max(pool.Capacity.Sample(rng), pool.MinMbps)
+			if pool.MaxMbps > 0 {
+				mbps = min(mbps, pool.MaxMbps)
+			}
+			p := &ServerParameters{
+				Mbps:           mbps,
+				OWD:            pool.OWD.Sample(rng),
+				MaxConnections: int(math.Floor(mbps / pool.Bitrate)),
+				OWDDist:        &pool.OWD,
+				QueueSize:      cfg.Cluster.Queue.Size,
+				SessionSlots:   cfg.Cluster.Sessions.Mode == "slot",
+				ReserveMbps:    cfg.Cluster.Sessions.Reserve,
+			}
+			s := &Server{
+				ID:         len(servers) + 1,
+				PoP:        pool.PoP,
+				Pool:       pool.Name,
+				Labels:     pool.Labels,
+				CurrentOWD: p.OWD,
+				Parameters: p,
+				Snapshots:  make([]*ServerSnapshot, 0),
+				mu:         sync.Mutex{},
+			}
+			if pool.PoP != "" {
+				s.Region = regions[pool.PoP]
+			} else if n := len(cfg.Geo.Regions); n > 0 {
+				s.Region = cfg.Geo.Regions[(s.ID-1)%n].Name
+			}
+			servers = append(servers, s)
+		}
+	}
+	return servers
+}
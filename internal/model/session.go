@@ -0,0 +1,27 @@
+package model
+
+// Session — видеосессия, для которой балансировщик выбирает сервер
+type Session struct {
+	ID        int64
+	UserID    int64   // пользователь (0 — совпадает с ID)
+	ContentID int     // ролик из каталога (0 — каталог не задан)
+	HashKey   int64   // ключ хеширующих балансировщиков (0 — пользователь)
+	Fragments int     // фактическая длина сессии во фрагментах
+	Hint      float64 // оценка длины от плеера во фрагментах (0 — подсказки нет)
+	Region    string  // регион клиента (пусто — география не задана)
+	Segment   int     // номер текущего запрашиваемого фрагмента
+
+	server *Server // сервер, к которому подключена сессия
+}
+
+// Key — ключ для хеширующих балансировщиков: по умолчанию сессии одного
+// пользователя попадают на один сервер
+func (s *Session) Key() int64 {
+	if s.HashKey != 0 {
+		return s.HashKey
+	}
+	if s.UserID != 0 {
+		return s.UserID
+	}
+	return s.ID
+}
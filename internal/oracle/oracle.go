@@ -0,0 +1,353 @@
+package oracle
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emrzvv/lb-research/internal/config"
+)
+
+// Result — итог офлайн-назначения и сравнение с онлайн-прогоном
+type Result struct {
+	Sessions      int
+	Segments      int     // сегменты, которые начались бы до конца симуляции
+	Served        int     // сегменты, обслуженные оракулом
+	Dropped       int     // сегменты, которые не помещаются ни на один сервер
+	MaxUtil       float64 // максимальная утилизация (ср. соединений в бине / max_conn)
+	OnlineServed  int     // сегменты, обслуженные в прогоне (строки requests.csv)
+	OnlineDropped int     // сегменты из Segments, не обслуженные в прогоне
+}
+
+// ServedPct — доля обслуженного онлайн-стратегией от обслуженного жадным
+// назначением, %; назначение не оптимально, поэтому доля может быть больше 100
+func (r *Result) ServedPct() float64 {
+	if r.Served == 0 {
+		return 0
+	}
+	return 100 * float64(r.OnlineServed) / float64(r.Served)
+}
+
+type session struct {
+	id        int64
+	t         float64
+	fragments int
+}
+
+type server struct {
+	cap      float64
+	duration float64 // время обслуживания запроса без очереди и штрафа за переброс, сек
+	off      []bool  // бины, в которых сервер хотя бы часть времени в резерве автомасштабирования
+}
+
+// available — сервер работает весь бин b
+func (srv *server) available(b int) bool {
+	return srv.off == nil || !srv.off[b]
+}
+
+// Run строит жадное назначение с полным знанием будущего по записанной нагрузке
+// (arrivals.csv, servers.csv, requests.csv, failures.csv в dir): сессии в порядке
+// поступления отправляются на сервер с минимальной пиковой утилизацией за время
+// сессии. Это эталон для сравнения, а не оптимум и не нижняя граница отказов.
+// Время дискретизируется бинами по step_seconds; сегмент занимает соединение
+// на duration/bin доли бина. Сервер в резерве автомасштабирования ёмкости не даёт.
+// Сегменты, не помещающиеся в ёмкость, считаются отказами.
+func Run(cfg *config.Config, dir string) (*Result, error) {
+	dir = strings.TrimSuffix(dir, "/")
+	sessions, err := readSessions(dir + "/arrivals.csv")
+	if err != nil {
+		return nil, err
+	}
+	reqs, err := readRequests(dir + "/requests.csv")
+	if err != nil {
+		return nil, err
+	}
+	servers, err := readServers(cfg, dir+"/servers.csv", reqs)
+	if err != nil {
+		return nil, err
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no servers in %s/servers.csv", dir)
+	}
+
+	bin := cfg.Simulation.StepSeconds
+	horizon := cfg.Simulation.TimeSeconds
+	bins := int(math.Ceil(horizon/bin)) + 1
+	if err := readStandby(cfg, dir+"/failures.csv", servers, bin, bins); err != nil {
+		return nil, err
+	}
+	occ := make([][]float64, len(servers))
+	for i := range occ {
+		occ[i] = make([]float64, bins)
+	}
+
+	res := &Result{Sessions: len(sessions), OnlineServed: len(reqs.sessions)}
+	served := make(map[int64]int)
+	for _, id := range reqs.sessions {
+		served[id]++
+	}
+	slots := make([]int, 0)
+	for _, ss := range sessions {
+		best, bestPeak := -1, math.MaxFloat64
+		for j := range servers {
+			srv := &servers[j]
+			if srv.cap <= 0 {
+				continue
+			}
+			slots = segmentBins(slots[:0], ss, srv.duration, cfg.Cluster.SegmentDuration, bin, horizon)
+			add := srv.duration / bin
+			peak := 0.0
+			for _, b := range slots {
+				u := math.Inf(1)
+				if srv.available(b) {
+					u = (occ[j][b] + add) / srv.cap
+				}
+				if u > peak {
+					peak = u
+					if peak >= bestPeak {
+						break
+					}
+				}
+			}
+			if peak < bestPeak {
+				best, bestPeak = j, peak
+			}
+		}
+
+		// ни один сервер не работает всё время сессии: её сегменты — отказы
+		best = max(best, 0)
+		srv := &servers[best]
+		slots = segmentBins(slots[:0], ss, srv.duration, cfg.Cluster.SegmentDuration, bin, horizon)
+		add := srv.duration / bin
+		res.Segments += len(slots)
+		res.OnlineDropped += max(len(slots)-served[ss.id], 0)
+		for _, b := range slots {
+			if !srv.available(b) || occ[best][b]+add > srv.cap {
+				res.Dropped++
+				continue
+			}
+			occ[best][b] += add
+			res.Served++
+		}
+	}
+
+	for j, srv := range servers {
+		if srv.cap <= 0 {
+			continue
+		}
+		for _, o := range occ[j] {
+			res.MaxUtil = math.Max(res.MaxUtil, o/srv.cap)
+		}
+	}
+
+	return res, nil
+}
+
+// segmentBins — бины, в которых начинаются сегменты сессии: сегмент k стартует
+// через duration+segment после предыдущего (передача, затем проигрывание)
+func segmentBins(dst []int, ss session, duration, segment, bin, horizon float64) []int {
+	t := ss.t
+	for k := 0; k < ss.fragments && t < horizon; k++ {
+		dst = append(dst, int(t/bin))
+		t += duration + segment
+	}
+	return dst
+}
+
+func WriteCSV(res *Result, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"sessions", "segments", "oracle_served", "oracle_dropped", "oracle_max_util",
+		"online_served", "online_dropped", "served_pct_of_greedy"})
+	w.Write([]string{
+		fmt.Sprintf("%d", res.Sessions),
+		fmt.Sprintf("%d", res.Segments),
+		fmt.Sprintf("%d", res.Served),
+		fmt.Sprintf("%d", res.Dropped),
+		fmt.Sprintf("%.5f", res.MaxUtil),
+		fmt.Sprintf("%d", res.OnlineServed),
+		fmt.Sprintf("%d", res.OnlineDropped),
+		fmt.Sprintf("%.2f", res.ServedPct()),
+	})
+	w.Flush()
+	return w.Error()
+}
+
+// readTable читает csv с заголовком и возвращает индексы колонок
+func readTable(path string, columns ...string) ([][]string, []int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("%s: empty file", path)
+	}
+	idx := make([]int, len(columns))
+	for i, c := range columns {
+		idx[i] = -1
+		for j, h := range rows[0] {
+			if h == c {
+				idx[i] = j
+			}
+		}
+		if idx[i] < 0 {
+			return nil, nil, fmt.Errorf("%s: no column %q", path, c)
+		}
+	}
+	return rows[1:], idx, nil
+}
+
+func readSessions(path string) ([]session, error) {
+	rows, idx, err := readTable(path, "time_s", "session_id", "fragments")
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]session, 0, len(rows))
+	for i, row := range rows {
+		t, err1 := strconv.ParseFloat(row[idx[0]], 64)
+		id, err2 := strconv.ParseInt(row[idx[1]], 10, 64)
+		n, err3 := strconv.Atoi(row[idx[2]])
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, fmt.Errorf("%s:%d: bad row %v", path, i+2, row)
+		}
+		sessions = append(sessions, session{id: id, t: t, fragments: n})
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].t < sessions[j].t })
+	return sessions, nil
+}
+
+// requests — обслуженные запросы прогона (requests.csv)
+type requests struct {
+	servers  []int     // сервер запроса
+	sessions []int64   // сессия запроса
+	service  []float64 // время обслуживания без очереди и штрафа за переброс, сек
+}
+
+func readRequests(path string) (*requests, error) {
+	rows, idx, err := readTable(path, "server_id", "session_id", "service")
+	if err != nil {
+		return nil, err
+	}
+	r := &requests{
+		servers:  make([]int, len(rows)),
+		sessions: make([]int64, len(rows)),
+		service:  make([]float64, len(rows)),
+	}
+	for i, row := range rows {
+		id, err1 := strconv.Atoi(row[idx[0]])
+		ss, err2 := strconv.ParseInt(row[idx[1]], 10, 64)
+		d, err3 := strconv.ParseFloat(row[idx[2]], 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, fmt.Errorf("%s:%d: bad row %v", path, i+2, row)
+		}
+		r.servers[i], r.sessions[i], r.service[i] = id, ss, d
+	}
+	return r, nil
+}
+
+// readServers берёт ёмкость из servers.csv, а время обслуживания — среднее
+// по requests.csv; для серверов без обслуженных запросов — оценку по mbps и owd
+func readServers(cfg *config.Config, path string, reqs *requests) ([]server, error) {
+	rows, idx, err := readTable(path, "id", "mbps", "owd_ms", "max_conn")
+	if err != nil {
+		return nil, err
+	}
+	servers := make([]server, len(rows))
+	ids := make(map[int]int, len(rows))
+	for i, row := range rows {
+		id, err1 := strconv.Atoi(row[idx[0]])
+		mbps, err2 := strconv.ParseFloat(row[idx[1]], 64)
+		owd, err3 := strconv.ParseFloat(row[idx[2]], 64)
+		maxConn, err4 := strconv.Atoi(row[idx[3]])
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			return nil, fmt.Errorf("%s:%d: bad row %v", path, i+2, row)
+		}
+		ids[id] = i
+		servers[i].cap = float64(maxConn)
+		if mbps > 0 {
+			servers[i].duration = cfg.Cluster.SegmentSizeBytes*8/(mbps*1_000_000) + 2*owd/1000.0
+		}
+	}
+
+	sum := make([]float64, len(servers))
+	cnt := make([]int, len(servers))
+	for i, id := range reqs.servers {
+		if j, ok := ids[id]; ok {
+			sum[j] += reqs.service[i]
+			cnt[j]++
+		}
+	}
+	for j := range servers {
+		if cnt[j] > 0 {
+			servers[j].duration = sum[j] / float64(cnt[j])
+		}
+	}
+	return servers, nil
+}
+
+// readStandby отмечает бины, в которых серверы резерва автомасштабирования
+// (id > cluster.servers) выключены: резерв выключен с начала прогона, дальше
+// состояние берётся из failures.csv (файла нет — состояние не менялось)
+func readStandby(cfg *config.Config, path string, servers []server, bin float64, bins int) error {
+	if cfg.Autoscale.MaxServers == 0 {
+		return nil
+	}
+	offSince := make([]float64, len(servers)) // < 0 — сервер не в резерве
+	for i := range servers {
+		offSince[i] = -1
+		if i >= cfg.Cluster.Servers {
+			offSince[i] = 0
+		}
+	}
+	mark := func(i int, t0, t1 float64) {
+		if servers[i].off == nil {
+			servers[i].off = make([]bool, bins)
+		}
+		for b := int(t0 / bin); b <= min(int(t1/bin), bins-1); b++ {
+			servers[i].off[b] = true
+		}
+	}
+
+	rows, idx, err := readTable(path, "time_s", "server_id", "state")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for n, row := range rows {
+		t, err1 := strconv.ParseFloat(row[idx[0]], 64)
+		id, err2 := strconv.Atoi(row[idx[1]])
+		if err1 != nil || err2 != nil || id < 1 || id > len(servers) {
+			return fmt.Errorf("%s:%d: bad row %v", path, n+2, row)
+		}
+		i := id - 1
+		switch off := row[idx[2]] == "off"; {
+		case off && offSince[i] < 0:
+			offSince[i] = t
+		case !off && offSince[i] >= 0:
+			mark(i, offSince[i], t)
+			offSince[i] = -1
+		}
+	}
+	for i, t := range offSince {
+		if t >= 0 {
+			mark(i, t, float64(bins)*bin)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package oracle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/config"
+)
+
+// writeRun записывает выгрузку прогона: пять сессий по три сегмента и два сервера
+// по одному соединению; запрос обслуживается 0.5 с, ещё 0.4 с — штраф за переброс
+func writeRun(t *testing.T, extra map[string]string) (*config.Config, string) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"arrivals.csv": "time_s,session_id,fragments\n0.1,1,3\n0.2,2,3\n0.3,3,3\n0.4,4,3\n0.45,5,3\n",
+		"servers.csv":  "id,mbps,owd_ms,max_conn\n1,100,0,1\n2,100,0,1\n",
+		"requests.csv": "server_id,session_id,start_s,end_s,duration,service\n1,1,0.1,1.0,0.9,0.5\n2,2,0.2,1.1,0.9,0.5\n",
+	}
+	for name, data := range extra {
+		files[name] = data
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{}
+	cfg.Simulation.TimeSeconds = 100
+	cfg.Simulation.StepSeconds = 1
+	cfg.Cluster.SegmentDuration = 6
+	return cfg, dir
+}
+
+func TestOracleGreedy(t *testing.T) {
+	cfg, dir := writeRun(t, nil)
+	res, err := Run(cfg, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// запрос занимает 0.5 бина (без штрафа за переброс):
+	// в бин помещаются 4 запроса, все сегменты пятой сессии — отказы
+	if res.Segments != 15 || res.Served != 12 || res.Dropped != 3 {
+		t.Fatalf("segments/served/dropped = %d/%d/%d, want 15/12/3", res.Segments, res.Served, res.Dropped)
+	}
+	if res.MaxUtil != 1 {
+		t.Fatalf("max util = %v, want 1", res.MaxUtil)
+	}
+	// онлайн обслужено по одному сегменту сессий 1 и 2, остальные 13 сегментов — отказы
+	if res.OnlineServed != 2 || res.OnlineDropped != 13 {
+		t.Fatalf("online served/dropped = %d/%d, want 2/13", res.OnlineServed, res.OnlineDropped)
+	}
+}
+
+func TestOracleSkipsStandby(t *testing.T) {
+	cfg, dir := writeRun(t, map[string]string{
+		"failures.csv": "time_s,server_id,state\n2.5,2,active\n",
+	})
+	cfg.Cluster.Servers = 1
+	cfg.Autoscale.MaxServers = 2
+
+	res, err := Run(cfg, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// сервер 2 в резерве до 2.5 с: первые сегменты всех сессий приходятся
+	// на сервер 1, на нём помещаются только сессии 1 и 2
+	if res.Segments != 15 || res.Served != 6 || res.Dropped != 9 {
+		t.Fatalf("segments/served/dropped = %d/%d/%d, want 15/6/9", res.Segments, res.Served, res.Dropped)
+	}
+}
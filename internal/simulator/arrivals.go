@@ -0,0 +1,120 @@
+package simulator
+
+import (
+	"math"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+)
+
+// arrivalProcess — процесс поступления сессий (traffic.arrivals.process).
+// Интервалы измеряются в «работе» — ожидаемом числе поступлений при текущей
+// интенсивности профиля (средний интервал — 1); в секунды их переводит waitWork
+type arrivalProcess interface {
+	// next — работа до следующего события и число сессий, поступающих в него
+	// (0 — поступлений нет, например смена состояния mmpp); base — base_rps,
+	// для перевода заданных в секундах параметров процесса
+	next(base float64) (float64, int)
+}
+
+func newArrivalProcess(cfg *config.Config, rng *common.RNG) arrivalProcess {
+	a := &cfg.Traffic.Arrivals
+	switch a.Process {
+	case "mmpp":
+		p := &mmppArrivals{rng: rng, cfg: cfg}
+		p.left = rng.ExpFloat64() * a.MMPP[0].Duration
+		return p
+	case "hawkes":
+		return &hawkesArrivals{rng: rng, branching: a.Branching, decay: a.Decay}
+	case "batch":
+		return &batchArrivals{rng: rng, size: &a.BatchSize, mean: a.BatchSize.Expected()}
+	case "pareto":
+		return &paretoArrivals{rng: rng, shape: a.Shape}
+	}
+	return &poissonArrivals{rng: rng}
+}
+
+type poissonArrivals struct {
+	rng *common.RNG
+}
+
+func (p *poissonArrivals) next(float64) (float64, int) {
+	return p.rng.ExpFloat64(), 1
+}
+
+// paretoArrivals — интервалы Парето со средним 1 (тяжёлый хвост пауз и сгущения)
+type paretoArrivals struct {
+	rng   *common.RNG
+	shape float64
+}
+
+func (p *paretoArrivals) next(float64) (float64, int) {
+	xm := (p.shape - 1) / p.shape
+	return xm / math.Pow(1-p.rng.Float64(), 1/p.shape), 1
+}
+
+// batchArrivals — пачки сессий; пачки поступают в E[batch_size] раз реже
+type batchArrivals struct {
+	rng  *common.RNG
+	size *config.Dist
+	mean float64
+}
+
+func (p *batchArrivals) next(float64) (float64, int) {
+	w := p.rng.ExpFloat64() * p.mean
+	return w, max(1, int(p.size.Sample(p.rng)))
+}
+
+// mmppArrivals — пуассоновский поток, интенсивность которого умножается на factor
+// текущего состояния марковской цепи; время пребывания задано в секундах при base_rps
+type mmppArrivals struct {
+	rng   *common.RNG
+	cfg   *config.Config
+	state int
+	left  float64 // оставшееся время в текущем состоянии, сек
+}
+
+func (p *mmppArrivals) next(base float64) (float64, int) {
+	states := p.cfg.Traffic.Arrivals.MMPP
+	// поток без памяти: интервал, не уложившийся в состояние, разыгрывается заново в следующем
+	if f := states[p.state].Factor; f > 0 {
+		if w := p.rng.ExpFloat64() / f; w < p.left*base {
+			p.left -= w / base
+			return w, 1
+		}
+	}
+	w := p.left * base
+	next := p.rng.Intn(len(states) - 1)
+	if next >= p.state {
+		next++
+	}
+	p.state = next
+	p.left = p.rng.ExpFloat64() * states[p.state].Duration
+	return w, 0
+}
+
+// hawkesArrivals — самовозбуждающийся поток: каждая сессия добавляет к интенсивности
+// branching/decay·exp(-Δt/decay); фоновая интенсивность 1 - branching, так что средняя
+// равна 1. Моделируется прореживанием (Ogata); decay задан в секундах при base_rps
+type hawkesArrivals struct {
+	rng       *common.RNG
+	branching float64
+	decay     float64
+	excite    float64 // возбуждение в момент последнего поступления
+}
+
+func (p *hawkesArrivals) next(base float64) (float64, int) {
+	mu := 1 - p.branching
+	decay := p.decay * base
+	elapsed := 0.0
+	for {
+		bound := mu + p.excite // возбуждение только убывает до следующего поступления
+		w := p.rng.ExpFloat64() / bound
+		elapsed += w
+		p.excite *= math.Exp(-w / decay)
+		if p.rng.Float64()*bound <= mu+p.excite {
+			p.excite += p.branching / decay
+			return elapsed, 1
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+)
+
+func TestArrivalProcessesKeepMeanRate(t *testing.T) {
+	const base = 100.0
+	configs := map[string]func(a *config.Config){
+		"poisson": func(*config.Config) {},
+		"pareto":  func(c *config.Config) { c.Traffic.Arrivals.Shape = 2.5 },
+		"batch": func(c *config.Config) {
+			c.Traffic.Arrivals.BatchSize = config.Dist{Type: "uniform", Min: 1, Max: 5, Int: true}
+		},
+		"mmpp": func(c *config.Config) {
+			// средний множитель по времени: (0.5·2 + 0·1 + 2.5·1) / 4 = 0.875
+			c.Traffic.Arrivals.MMPP = []struct {
+				Factor   float64 `yaml:"factor"`
+				Duration float64 `yaml:"mean_duration_s"`
+			}{{Factor: 0.5, Duration: 2}, {Factor: 0, Duration: 1}, {Factor: 2.5, Duration: 1}}
+		},
+		"hawkes": func(c *config.Config) {
+			c.Traffic.Arrivals.Branching = 0.6
+			c.Traffic.Arrivals.Decay = 0.5
+		},
+	}
+	for process, setup := range configs {
+		cfg := &config.Config{}
+		cfg.Traffic.Arrivals.Process = process
+		setup(cfg)
+		want := 1.0
+		if process == "mmpp" {
+			// при равновероятных переходах доля времени в состоянии пропорциональна его длительности
+			want = 0.875
+		}
+
+		p := newArrivalProcess(cfg, common.NewRNG(7))
+		work, sessions := 0.0, 0
+		for work < 1e6 {
+			w, n := p.next(base)
+			if w < 0 || math.IsInf(w, 0) || math.IsNaN(w) {
+				t.Fatalf("%s: invalid interval %v", process, w)
+			}
+			work += w
+			sessions += n
+		}
+		if got := float64(sessions) / work; math.Abs(got-want) > 0.03*want {
+			t.Errorf("%s: mean rate = %.4f, want %.4f", process, got, want)
+		}
+	}
+}
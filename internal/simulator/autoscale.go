@@ -0,0 +1,110 @@
+package simulator
+
+import (
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+// autoscale — контроллер автомасштабирования. Раз в interval_s считает утилизацию
+// работающих серверов по последним снимкам: выше target включает step серверов
+// из резерва (запуск provision_s, затем warmup_s на сниженной пропускной
+// способности), ниже low выводит наименее загруженный сервер и после ухода
+// сессий возвращает его в резерв. Пока серверы запускаются, новые не добавляются;
+// вывод возможен не раньше cooldown_s после последнего решения
+func autoscale(
+	proc simgo.Process,
+	cfg *config.Config,
+	servers []*model.Server,
+	st *stats.Statistics) {
+
+	a := &cfg.Autoscale
+	provisioning := make([]bool, len(servers))
+	pending := 0
+	lastScale := 0.0 // на старте кластер пуст: не выводить серверы до cooldown_s
+
+	for proc.Now() < cfg.Simulation.TimeSeconds {
+		proc.Wait(proc.Timeout(a.Interval))
+		now := proc.Now()
+
+		used, capacity, active := 0, 0, 0
+		var victim *model.Server
+		victimLoad := 0
+		for _, s := range servers {
+			if !s.IsAvailable() {
+				continue
+			}
+			u, c := s.SnapshotLoad()
+			used += u
+			capacity += c
+			active++
+			// при равной нагрузке выводится сервер с большим id (добавленный последним)
+			if load := s.Load(); victim == nil || load <= victimLoad {
+				victim, victimLoad = s, load
+			}
+		}
+		util := 0.0
+		if capacity > 0 {
+			util = float64(used) / float64(capacity)
+		}
+
+		action := ""
+		switch {
+		case (util > a.Target || active == 0) && pending == 0:
+			for i, s := range servers {
+				if pending == a.Step || active+pending >= a.MaxServers {
+					break
+				}
+				if provisioning[i] || !s.IsOff() {
+					continue
+				}
+				provisioning[i] = true
+				pending++
+				proc.Process(func(proc simgo.Process) {
+					proc.Wait(proc.Timeout(a.Provision))
+					s.PowerOn(proc.Now(), st)
+					provisioning[i] = false
+					pending--
+					if a.Warmup > 0 {
+						s.Degrade(a.WarmupBW, 1)
+						proc.Wait(proc.Timeout(a.Warmup))
+						s.Restore(a.WarmupBW, 1)
+					}
+				})
+			}
+			if pending > 0 {
+				action = "out"
+				lastScale = now
+			}
+		case util < a.Low && active > a.MinServers && now-lastScale >= a.Cooldown:
+			s := victim
+			deadline := 0.0
+			if a.Drain > 0 {
+				deadline = now + a.Drain
+			}
+			drained := s.Drain(proc, deadline, st)
+			proc.Process(func(proc simgo.Process) {
+				if drained != nil {
+					if a.Drain > 0 {
+						grace := a.Drain + float64(cfg.Cluster.SegmentDuration)
+						proc.Wait(proc.AnyOf(drained, proc.Timeout(grace)))
+					} else {
+						proc.Wait(drained)
+					}
+				}
+				s.PowerOff(proc.Now(), st)
+			})
+			action = "in"
+			lastScale = now
+		}
+
+		st.AddScale(&stats.ScaleEvent{
+			T:            now,
+			Active:       active,
+			Provisioning: pending,
+			Utilisation:  util,
+			Action:       action,
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+func TestAutoscaleOutAndIn(t *testing.T) {
+	cfg := loadConfig(t, `
+simulation: {time_seconds: 40}
+cluster: {servers: 2}
+autoscale:
+  max_servers: 4
+  interval_s: 1
+  provision_s: 5
+  warmup_s: 3
+  warmup_bandwidth: 0.5
+  cooldown_s: 10
+`)
+	servers := model.InitServers(cfg, common.NewRNG(1))
+	st := stats.NewStatistics(cfg)
+	sim := simgo.NewSimulation()
+	sim.Process(func(proc simgo.Process) { collectSnapshots(proc, cfg, servers) })
+	sim.Process(func(proc simgo.Process) { autoscale(proc, cfg, servers, st) })
+
+	// нагрузка: половина соединений, в 10.5 — все соединения двух серверов, в 18.5 — ни одного
+	load := func(share float64) {
+		for _, s := range servers[:2] {
+			s.Lock()
+			s.CurrentConnections = int(share * float64(s.Parameters.MaxConnections))
+			s.Unlock()
+		}
+	}
+	type probe struct {
+		t        float64
+		off      bool
+		degraded bool
+	}
+	var probes []probe
+	sim.Process(func(proc simgo.Process) {
+		load(0.5)
+		for proc.Now() < cfg.Simulation.TimeSeconds {
+			switch proc.Now() {
+			case 10.5:
+				load(1)
+			case 18.5:
+				load(0)
+			}
+			probes = append(probes, probe{proc.Now(), servers[2].IsOff(), servers[2].IsDegraded()})
+			proc.Wait(proc.Timeout(0.25))
+		}
+	})
+	sim.RunUntil(cfg.Simulation.TimeSeconds)
+	sim.Shutdown()
+
+	var out, in []float64
+	for _, ev := range st.Scaling {
+		switch ev.Action {
+		case "out":
+			out = append(out, ev.T)
+		case "in":
+			in = append(in, ev.T)
+		}
+	}
+	if len(out) != 1 || out[0] < 11 || out[0] > 12 {
+		t.Fatalf("scale-out at %v, want once right after the load step at 10.5", out)
+	}
+	// сервер 3 включается через provision_s и прогревается warmup_s на сниженной полосе
+	on := -1.0
+	for _, p := range probes {
+		if !p.off && on < 0 {
+			on = p.t
+		}
+		switch {
+		case p.t < out[0]+5 && !p.off:
+			t.Fatalf("server 3 is on at %v before provisioning ends", p.t)
+		case p.t > out[0]+5 && p.t < out[0]+5+3 && !p.degraded:
+			t.Fatalf("server 3 is not warming up at %v", p.t)
+		case p.t > out[0]+5+3 && p.degraded:
+			t.Fatalf("server 3 is still warming up at %v", p.t)
+		}
+	}
+	if math.Abs(on-(out[0]+5)) > 0.25 {
+		t.Fatalf("server 3 powered on at %v, want %v", on, out[0]+5)
+	}
+	// нагрузка упала в 18.5, но вывод — не раньше cooldown_s после добавления
+	if len(in) != 1 || in[0] < out[0]+10 || in[0] > out[0]+11 {
+		t.Fatalf("scale-in at %v, want once %v s after the scale-out at %v", in, 10, out[0])
+	}
+	if !servers[2].IsOff() || !servers[3].IsOff() {
+		t.Fatalf("servers 3, 4 off = %v, %v, want both back in standby", servers[2].IsOff(), servers[3].IsOff())
+	}
+}
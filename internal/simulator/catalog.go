@@ -0,0 +1,52 @@
+package simulator
+
+import (
+	"math"
+	"sort"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+)
+
+// zipfCDF — накопленные веса rank^(-s) для рангов 1..n
+func zipfCDF(n int, s float64) []float64 {
+	cdf := make([]float64, n)
+	acc := 0.0
+	for i := range cdf {
+		acc += math.Pow(float64(i+1), -s)
+		cdf[i] = acc
+	}
+	return cdf
+}
+
+// sampleCDF — ранг (с 1) пропорционально весам накопленного cdf
+func sampleCDF(cdf []float64, rng *common.RNG) int {
+	x := rng.Float64() * cdf[len(cdf)-1]
+	return sort.SearchFloat64s(cdf, x) + 1
+}
+
+// catalog — каталог контента (traffic.catalog): ролик с номером i имеет i-ю по величине популярность
+type catalog struct {
+	rng     *common.RNG
+	cdf     []float64
+	lengths []int // длина ролика во фрагментах, по номеру-1
+}
+
+// newCatalog — каталог роликов; nil — каталог не задан
+func newCatalog(cfg *config.Config, rng *common.RNG) *catalog {
+	cat := &cfg.Traffic.Catalog
+	if cat.Items == 0 {
+		return nil
+	}
+	c := &catalog{rng: rng, cdf: zipfCDF(cat.Items, cat.Zipf), lengths: make([]int, cat.Items)}
+	for i := range c.lengths {
+		c.lengths[i] = max(1, int(cat.Length.Sample(rng)))
+	}
+	return c
+}
+
+// choose — ролик новой сессии и его длина
+func (c *catalog) choose() (int, int) {
+	id := sampleCDF(c.cdf, c.rng)
+	return id, c.lengths[id-1]
+}
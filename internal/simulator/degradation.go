@@ -0,0 +1,73 @@
+package simulator
+
+import (
+	"slices"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+// degradationTick периодически ухудшает группу серверов (один сервер или все
+// серверы метки) на время из degradation.duration; время между деградациями —
+// экспоненциальное со средним mtbd_s (label_mtbd_s для меток)
+func degradationTick(
+	proc simgo.Process,
+	cfg *config.Config,
+	group []*model.Server,
+	label string,
+	st *stats.Statistics,
+	rng *common.RNG) {
+
+	d := &cfg.Degradation
+	mtbd := d.MTBD
+	if label != "" {
+		mtbd = d.LabelMTBD
+	}
+	for {
+		proc.Wait(proc.Timeout(rng.ExpFloat64() * mtbd))
+		now := proc.Now()
+		if now >= cfg.Simulation.TimeSeconds {
+			return
+		}
+		// множители ограничены так, чтобы деградация только ухудшала сервер
+		bandwidth := min(max(d.Bandwidth.Sample(rng), 0.01), 1)
+		latency := max(d.Latency.Sample(rng), 1)
+		duration := max(d.Duration.Sample(rng), 0)
+
+		// выключенные серверы (отказ, резерв автомасштабирования) не деградируют
+		var degraded []*model.Server
+		for _, s := range group {
+			if s.IsDown() {
+				continue
+			}
+			degraded = append(degraded, s)
+			s.Degrade(bandwidth, latency)
+			st.AddDegradation(&stats.DegradationEvent{
+				T1:        now,
+				T2:        min(now+duration, cfg.Simulation.TimeSeconds),
+				ServerID:  s.ID,
+				Label:     label,
+				Bandwidth: bandwidth,
+				Latency:   latency,
+			})
+		}
+		proc.Wait(proc.Timeout(duration))
+		for _, s := range degraded {
+			s.Restore(bandwidth, latency)
+		}
+	}
+}
+
+// labelled — серверы с меткой label
+func labelled(servers []*model.Server, label string) []*model.Server {
+	var group []*model.Server
+	for _, s := range servers {
+		if slices.Contains(s.Labels, label) {
+			group = append(group, s)
+		}
+	}
+	return group
+}
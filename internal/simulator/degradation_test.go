@@ -0,0 +1,39 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+func TestDegradationSkipsDownServersAndEndsWithRun(t *testing.T) {
+	cfg := loadConfig(t, `
+simulation: {time_seconds: 50}
+cluster: {servers: 2}
+degradation:
+  mtbd_s: 5
+  duration: {type: constant, value: 1000}
+`)
+	rng := common.NewRNG(1)
+	servers := model.InitServers(cfg, rng)
+	st := stats.NewStatistics(cfg)
+	servers[1].Fail(0, st, "failure")
+
+	sim := simgo.NewSimulation()
+	sim.Process(func(proc simgo.Process) { degradationTick(proc, cfg, servers, "rack", st, rng) })
+	sim.RunUntil(cfg.Simulation.TimeSeconds)
+	sim.Shutdown()
+
+	if len(st.Degradations) != 1 {
+		t.Fatalf("degradations = %d, want 1 (server 2 is down)", len(st.Degradations))
+	}
+	if ev := st.Degradations[0]; ev.ServerID != 1 || ev.T2 != cfg.Simulation.TimeSeconds {
+		t.Fatalf("degradation = %+v, want server 1 until the end of the run", ev)
+	}
+	if servers[1].IsDegraded() {
+		t.Fatalf("down server 2 is degraded")
+	}
+}
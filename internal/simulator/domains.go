@@ -0,0 +1,76 @@
+package simulator
+
+import (
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+// domainFailureTick выключает все серверы домена разом; время до отказа и
+// восстановления — экспоненциальные со средними mtbf и mttr. Восстановление домена
+// не возвращает серверы, выключенные и по другим причинам (отказ сервера, сценарий)
+func domainFailureTick(
+	proc simgo.Process,
+	cfg *config.Config,
+	name string,
+	group []*model.Server,
+	mtbf, mttr float64,
+	st *stats.Statistics,
+	rng *common.RNG) {
+
+	for {
+		proc.Wait(proc.Timeout(rng.ExpFloat64() * mtbf))
+		now := proc.Now()
+		if now >= cfg.Simulation.TimeSeconds {
+			return
+		}
+		owner := "domain " + name
+		for _, s := range group {
+			s.Fail(now, st, owner)
+		}
+
+		repair := rng.ExpFloat64() * mttr
+		st.AddDomainEvent(&stats.DomainEvent{
+			T1:     now,
+			T2:     min(now+repair, cfg.Simulation.TimeSeconds),
+			Domain: name,
+			Kind:   "fail",
+		})
+		proc.Wait(proc.Timeout(repair))
+		for _, s := range group {
+			s.Recover(proc.Now(), st, owner)
+		}
+	}
+}
+
+// domainSpikeTick на каждом тике jitter с вероятностью prob увеличивает OWD
+// всех серверов домена на extra мс на время duration
+func domainSpikeTick(
+	proc simgo.Process,
+	cfg *config.Config,
+	name string,
+	group []*model.Server,
+	prob, extra, duration float64,
+	st *stats.Statistics,
+	rng *common.RNG) {
+
+	for proc.Now() < cfg.Simulation.TimeSeconds {
+		proc.Wait(proc.Timeout(cfg.Jitter.Tick))
+		if rng.Float64() >= prob {
+			continue
+		}
+		now := proc.Now()
+		for _, s := range group {
+			s.Spike(now+duration, extra)
+		}
+		st.AddDomainEvent(&stats.DomainEvent{
+			T1:     now,
+			T2:     min(now+duration, cfg.Simulation.TimeSeconds),
+			Domain: name,
+			Kind:   "spike",
+		})
+		proc.Wait(proc.Timeout(duration))
+	}
+}
@@ -0,0 +1,65 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+func TestDomainFailureOwnsItsDowntime(t *testing.T) {
+	cfg := loadConfig(t, `
+simulation: {time_seconds: 100}
+cluster: {servers: 3}
+domains: [{name: rack, servers: [1, 2, 3], mtbf_s: 10, mttr_s: 5}]
+`)
+	rng := common.NewRNG(1)
+	servers := model.InitServers(cfg, rng)
+	st := stats.NewStatistics(cfg)
+	sim := simgo.NewSimulation()
+	sim.Process(func(proc simgo.Process) {
+		domainFailureTick(proc, cfg, "rack", servers, 10, 5, st, rng)
+	})
+
+	// 2 и 3 отказали сами до отказа домена; 2 отремонтирован во время отказа домена, 3 — нет
+	var during, after []bool
+	sim.Process(func(proc simgo.Process) {
+		servers[1].Fail(proc.Now(), st, "failure")
+		servers[2].Fail(proc.Now(), st, "failure")
+		for !servers[0].IsDown() {
+			proc.Wait(proc.Timeout(0.01))
+		}
+		servers[1].Recover(proc.Now(), st, "failure")
+		for _, s := range servers {
+			during = append(during, s.IsDown())
+		}
+		for servers[0].IsDown() {
+			proc.Wait(proc.Timeout(0.01))
+		}
+		for _, s := range servers {
+			after = append(after, s.IsDown())
+		}
+	})
+	sim.RunUntil(cfg.Simulation.TimeSeconds)
+	sim.Shutdown()
+
+	if len(st.DomainEvents) == 0 || len(after) == 0 {
+		t.Fatalf("domain did not fail and recover within %v s", cfg.Simulation.TimeSeconds)
+	}
+	for i, down := range during {
+		if !down {
+			t.Errorf("server %d is up during the domain outage", i+1)
+		}
+	}
+	if after[0] || after[1] || !after[2] {
+		t.Errorf("down after the domain recovery = %v, want only server 3", after)
+	}
+	// восстановление домена не пишет active для сервера, который остаётся выключенным
+	for _, ev := range st.States {
+		if ev.ServerID == 3 && ev.State == "active" {
+			t.Errorf("server 3 became active at %v", ev.T)
+		}
+	}
+}
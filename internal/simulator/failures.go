@@ -0,0 +1,30 @@
+package simulator
+
+import (
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+// failureTick чередует работу и отказы сервера: время до отказа и время
+// восстановления — экспоненциальные со средними mtbf_s и mttr_s
+func failureTick(
+	proc simgo.Process,
+	cfg *config.Config,
+	server *model.Server,
+	st *stats.Statistics,
+	rng *common.RNG) {
+
+	for {
+		proc.Wait(proc.Timeout(rng.ExpFloat64() * cfg.Failures.MTBF))
+		if proc.Now() >= cfg.Simulation.TimeSeconds {
+			return
+		}
+		server.Fail(proc.Now(), st, "failure")
+
+		proc.Wait(proc.Timeout(rng.ExpFloat64() * cfg.Failures.MTTR))
+		server.Recover(proc.Now(), st, "failure")
+	}
+}
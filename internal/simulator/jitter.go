@@ -13,25 +13,26 @@ func jitterTick(
 	server *model.Server,
 	rng *common.RNG) {
 
-	base := server.Parameters.OWD
 	for proc.Now() < cfg.Simulation.TimeSeconds {
 		proc.Wait(proc.Timeout(cfg.Jitter.Tick))
 		server.Lock()
 		now := proc.Now()
+		base := server.Parameters.OWD + server.Parameters.OWDExtra
 		if now < server.SpikeUntil {
-			server.CurrentOWD = base + cfg.Jitter.SpikeExtra
+			server.SetOWD(base + server.SpikeExtra)
 			server.Unlock()
 			continue
 		}
 
 		if rng.Float64() < cfg.Jitter.SpikeP {
 			server.SpikeUntil = now + cfg.Jitter.SpikeDur
-			server.CurrentOWD = base + cfg.Jitter.SpikeExtra
+			server.SpikeExtra = cfg.Jitter.SpikeExtra
+			server.SetOWD(base + cfg.Jitter.SpikeExtra)
 			server.Unlock()
 			continue
 		}
 
-		server.CurrentOWD = model.RandGamma(cfg.Cluster.OWDMean, cfg.Cluster.OWDCV, rng)
+		server.SetOWD(server.Parameters.OWDDist.Sample(rng) + server.Parameters.OWDExtra)
 		server.Unlock()
 	}
 }
@@ -0,0 +1,67 @@
+package simulator
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/fschuetz04/simgo"
+)
+
+func TestJitterKeepsTopologyOWD(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"servers.csv": "id,mbps,owd_ms,owd_jitter_ms\n1,100,5,\n2,100,200,20\n",
+		"cfg.yaml":    "simulation: {time_seconds: 2000}\ncluster: {owd: {type: constant, value: 50}}\ntopology: {servers_file: servers.csv}\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := config.Load(filepath.Join(dir, "cfg.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Jitter.SpikeP = 0
+	rng := common.NewRNG(1)
+	servers := model.InitServers(cfg, rng)
+
+	sim := simgo.NewSimulation()
+	samples := make([][]float64, len(servers))
+	for i, s := range servers {
+		sim.Process(func(proc simgo.Process) { jitterTick(proc, cfg, s, rng) })
+		sim.Process(func(proc simgo.Process) {
+			for {
+				proc.Wait(proc.Timeout(cfg.Jitter.Tick))
+				s.Lock()
+				samples[i] = append(samples[i], s.CurrentOWD)
+				s.Unlock()
+			}
+		})
+	}
+	sim.RunUntil(cfg.Simulation.TimeSeconds)
+	sim.Shutdown()
+
+	// без owd_jitter_ms задержка не меняется, с ним — колеблется вокруг измеренной, а не cluster.owd
+	for _, owd := range samples[0] {
+		if owd != 5 {
+			t.Fatalf("server 1: owd = %v, want constant 5", owd)
+		}
+	}
+	mean, sq := 0.0, 0.0
+	for _, owd := range samples[1] {
+		mean += owd
+		sq += owd * owd
+	}
+	n := float64(len(samples[1]))
+	mean /= n
+	std := math.Sqrt(sq/n - mean*mean)
+	if math.Abs(mean-200) > 3 || math.Abs(std-20) > 3 {
+		t.Fatalf("server 2: owd mean = %.1f, std = %.1f, want about 200 and 20", mean, std)
+	}
+}
@@ -0,0 +1,102 @@
+package simulator
+
+import (
+	"math"
+
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+// profileRate — интенсивность поступления сессий в момент t по traffic.profile и spikes:
+// base_rps × diurnal × ramps × Π(mul) + base_rps × Σ(add). После окончания всплеска
+// его вклад спадает экспоненциально с постоянной decay_s
+func profileRate(cfg *config.Config, t float64) float64 {
+	p := &cfg.Traffic.Profile
+	mul := 1.0
+	if a := p.Diurnal.Amplitude; a > 0 {
+		mul *= 1 + a*math.Sin(2*math.Pi*(t+p.Diurnal.Phase)/p.Diurnal.Period)
+	}
+	mul *= rampFactor(cfg, t)
+
+	add := 0.0
+	for _, sp := range cfg.Spikes {
+		w := spikeWeight(sp.At, sp.Duration, sp.Decay, t)
+		if w == 0 {
+			continue
+		}
+		if sp.Mode == "add" {
+			add += sp.Factor * w
+		} else {
+			mul *= 1 + (sp.Factor-1)*w
+		}
+	}
+	return cfg.Traffic.BaseRPS*mul + cfg.Traffic.BaseRPS*add
+}
+
+// spikeWeight — доля всплеска в момент t: 1 во время всплеска, затем exp(-Δt/decay)
+func spikeWeight(at, duration, decay, t float64) float64 {
+	end := at + duration
+	switch {
+	case t < at:
+		return 0
+	case t < end:
+		return 1
+	case decay > 0:
+		return math.Exp(-(t - end) / decay)
+	}
+	return 0
+}
+
+// rampFactor — кусочно-линейный множитель traffic.profile.ramps
+func rampFactor(cfg *config.Config, t float64) float64 {
+	ramps := cfg.Traffic.Profile.Ramps
+	if len(ramps) == 0 {
+		return 1
+	}
+	if t <= ramps[0].At {
+		return ramps[0].Factor
+	}
+	for i := 1; i < len(ramps); i++ {
+		if t < ramps[i].At {
+			prev, next := ramps[i-1], ramps[i]
+			return prev.Factor + (next.Factor-prev.Factor)*(t-prev.At)/(next.At-prev.At)
+		}
+	}
+	return ramps[len(ramps)-1].Factor
+}
+
+// waitWork ждёт, пока при интенсивности rc не наберётся work ожидаемых поступлений.
+// Интенсивность пересчитывается не реже step_seconds: интервал, разыгранный во впадине
+// профиля, не перескакивает подъём или всплеск, а при нулевой интенсивности поток
+// приостанавливается до её возврата
+func waitWork(proc simgo.Process, cfg *config.Config, rc *rateCtrl, work float64) {
+	step := cfg.Simulation.StepSeconds
+	for {
+		rate := rc.Get()
+		if rate > 0 && work/rate <= step {
+			ia := work / rate
+			if ia < 1e-6 { // TODO: to config?
+				ia = 1e-6
+			}
+			proc.Wait(proc.Timeout(ia))
+			return
+		}
+		proc.Wait(proc.Timeout(step))
+		work -= rate * step
+	}
+}
+
+// recordRate сохраняет фактическую интенсивность (с множителем сценария) с шагом снимков
+func recordRate(
+	proc simgo.Process,
+	cfg *config.Config,
+	rc *rateCtrl,
+	st *stats.Statistics) {
+
+	step := cfg.Simulation.StepSeconds
+	for t := 0.0; t < cfg.Simulation.TimeSeconds; t += step {
+		st.AddRate(&stats.RateEvent{T: proc.Now(), RPS: rc.Get()})
+		proc.Wait(proc.Timeout(step))
+	}
+}
@@ -0,0 +1,90 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/fschuetz04/simgo"
+)
+
+type ramp = struct {
+	At     float64 `yaml:"at"`
+	Factor float64 `yaml:"factor"`
+}
+
+func TestProfileRate(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Traffic.BaseRPS = 100
+	cfg.Traffic.Profile.Ramps = []ramp{{At: 0, Factor: 1}, {At: 10, Factor: 0}, {At: 20, Factor: 1}}
+	for _, c := range []struct{ t, want float64 }{{-1, 1}, {5, 0.5}, {10, 0}, {15, 0.5}, {25, 1}} {
+		if got := rampFactor(cfg, c.t); math.Abs(got-c.want) > 1e-12 {
+			t.Errorf("rampFactor(%v) = %v, want %v", c.t, got, c.want)
+		}
+	}
+
+	for _, c := range []struct{ t, want float64 }{{4, 0}, {5, 1}, {7, 1}, {9, math.Exp(-0.5)}} {
+		if got := spikeWeight(5, 3, 2, c.t); math.Abs(got-c.want) > 1e-12 {
+			t.Errorf("spikeWeight(%v) = %v, want %v", c.t, got, c.want)
+		}
+	}
+	if got := spikeWeight(5, 3, 0, 8); got != 0 {
+		t.Errorf("spikeWeight without decay after end = %v, want 0", got)
+	}
+
+	// пересекающиеся всплески: mul перемножаются, add складываются в долях base_rps
+	cfg.Traffic.Profile.Ramps = nil
+	cfg.Spikes = []struct {
+		At       float64 `yaml:"at"`
+		Duration float64 `yaml:"duration"`
+		Factor   float64 `yaml:"factor"`
+		Mode     string  `yaml:"mode"`
+		Decay    float64 `yaml:"decay_s"`
+	}{
+		{At: 10, Duration: 10, Factor: 3, Mode: "mul"},
+		{At: 15, Duration: 10, Factor: 2, Mode: "mul"},
+		{At: 12, Duration: 5, Factor: 0.5, Mode: "add", Decay: 2},
+		{At: 14, Duration: 4, Factor: 1, Mode: "add"},
+	}
+	for _, c := range []struct{ t, want float64 }{
+		{5, 100},
+		{11, 300},
+		{16, 100*3*2 + 100*(0.5+1)},
+		{19, 100*3*2 + 100*0.5*math.Exp(-1)},
+		{22, 100*2 + 100*0.5*math.Exp(-2.5)},
+	} {
+		if got := profileRate(cfg, c.t); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("profileRate(%v) = %v, want %v", c.t, got, c.want)
+		}
+	}
+}
+
+func TestWaitWorkFollowsProfile(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Simulation.StepSeconds = 1
+	cfg.Traffic.BaseRPS = 0.2
+	// поступления приостановлены на [10, 20], после 20 — в 100 раз чаще
+	cfg.Traffic.Profile.Ramps = []ramp{{At: 10, Factor: 1}, {At: 10.01, Factor: 0}, {At: 20, Factor: 0}, {At: 20.01, Factor: 100}}
+
+	sim := simgo.NewSimulation()
+	rc := &rateCtrl{cfg: cfg, now: sim.Now, factor: 1}
+	var times []float64
+	sim.Process(func(proc simgo.Process) {
+		for {
+			// интервал, разыгранный при base_rps, длиннее всего прогона
+			waitWork(proc, cfg, rc, 10)
+			times = append(times, proc.Now())
+		}
+	})
+	sim.RunUntil(30)
+	sim.Shutdown()
+
+	// до паузы набирается около 2 из 10, после 20 интенсивность 20/с: первое поступление —
+	// в пределах шага после паузы, далее каждые 0.5 с
+	if len(times) == 0 || times[0] < 20 || times[0] > 22 {
+		t.Fatalf("arrivals = %v, want the first one right after the pause", times)
+	}
+	if len(times) < 15 {
+		t.Fatalf("arrivals after the pause = %d, want about 20", len(times))
+	}
+}
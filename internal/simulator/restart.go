@@ -0,0 +1,61 @@
+package simulator
+
+import (
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+// rollingRestart перезапускает серверы группами по rolling_restart.batch:
+// группа выводится из балансировки, после ухода всех сессий выключается на
+// downtime_s и возвращается в работу. При drain_timeout_s подключённые сессии
+// переносятся по истечении срока, а оставшиеся через ещё один фрагмент
+// передачи обрываются выключением сервера
+func rollingRestart(
+	proc simgo.Process,
+	cfg *config.Config,
+	servers []*model.Server,
+	st *stats.Statistics) {
+
+	rr := cfg.RollingRestart
+	if rr.Start > 0 {
+		proc.Wait(proc.Timeout(rr.Start))
+	}
+	for i := 0; i < len(servers); i += rr.Batch {
+		if proc.Now() >= cfg.Simulation.TimeSeconds {
+			return
+		}
+		batch := servers[i:min(i+rr.Batch, len(servers))]
+
+		deadline := 0.0
+		if rr.DrainTimeout > 0 {
+			deadline = proc.Now() + rr.DrainTimeout
+		}
+		var drained []simgo.Awaitable
+		for _, s := range batch {
+			if ev := s.Drain(proc, deadline, st); ev != nil {
+				drained = append(drained, ev)
+			}
+		}
+		if rr.DrainTimeout > 0 {
+			grace := rr.DrainTimeout + float64(cfg.Cluster.SegmentDuration)
+			proc.Wait(proc.AnyOf(proc.AllOf(drained...), proc.Timeout(grace)))
+		} else {
+			proc.Wait(proc.AllOf(drained...))
+		}
+
+		for _, s := range batch {
+			s.Fail(proc.Now(), st, "restart")
+		}
+		if rr.Downtime > 0 {
+			proc.Wait(proc.Timeout(rr.Downtime))
+		}
+		for _, s := range batch {
+			s.Recover(proc.Now(), st, "restart")
+		}
+		if rr.Interval > 0 {
+			proc.Wait(proc.Timeout(rr.Interval))
+		}
+	}
+}
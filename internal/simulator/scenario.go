@@ -0,0 +1,162 @@
+package simulator
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/emrzvv/lb-research/internal/balancer"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+// scenarioStep — действие сценария или его отмена по duration
+type scenarioStep struct {
+	at       float64
+	action   string
+	servers  []*model.Server
+	factor   float64
+	value    float64
+	strategy string
+	deadline float64
+	undo     bool
+}
+
+// scenarioSteps раскладывает scenario в упорядоченный по времени список шагов;
+// при равном времени шаги идут в порядке конфига
+func scenarioSteps(cfg *config.Config, servers []*model.Server) []scenarioStep {
+	var steps []scenarioStep
+	for _, ev := range cfg.Scenario {
+		targets := servers
+		if ev.Domain != "" {
+			targets = model.InDomain(servers, ev.Domain)
+		} else if len(ev.Servers) > 0 {
+			targets = make([]*model.Server, 0, len(ev.Servers))
+			for _, id := range ev.Servers {
+				targets = append(targets, servers[id-1])
+			}
+		}
+		step := scenarioStep{
+			at:       ev.At,
+			action:   ev.Action,
+			servers:  targets,
+			factor:   ev.Factor,
+			value:    ev.Value,
+			strategy: ev.Strategy,
+			deadline: ev.Deadline,
+		}
+		steps = append(steps, step)
+		if ev.Duration == 0 {
+			continue
+		}
+
+		undo := step
+		undo.at = ev.At + ev.Duration
+		undo.undo = true
+		switch ev.Action {
+		case "fail", "drain":
+			undo.action = "recover"
+		case "capacity", "traffic":
+			undo.factor = 1
+		case "owd":
+			undo.value = 0
+		case "strategy":
+			undo.strategy = cfg.Balancer.Strategy
+		}
+		steps = append(steps, undo)
+	}
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].at < steps[j].at })
+	return steps
+}
+
+// runScenario выполняет scenario: отказы, вывод и восстановление серверов, изменение
+// их пропускной способности и OWD, множитель трафика и смену стратегии
+func runScenario(
+	proc simgo.Process,
+	cfg *config.Config,
+	rc *rateCtrl,
+	b balancer.Balancer,
+	servers []*model.Server,
+	st *stats.Statistics) {
+
+	// capacity задаётся относительно исходных параметров серверов
+	mbps := make([]float64, len(servers))
+	maxConn := make([]int, len(servers))
+	for i, s := range servers {
+		mbps[i] = s.Parameters.Mbps
+		maxConn[i] = s.Parameters.MaxConnections
+	}
+
+	for _, step := range scenarioSteps(cfg, servers) {
+		if step.at >= cfg.Simulation.TimeSeconds {
+			return
+		}
+		if wait := step.at - proc.Now(); wait > 0 {
+			proc.Wait(proc.Timeout(wait))
+		}
+
+		value := ""
+		switch step.action {
+		case "fail":
+			for _, s := range step.servers {
+				s.Fail(proc.Now(), st, "scenario")
+			}
+		case "drain":
+			deadline := 0.0
+			if step.deadline > 0 {
+				deadline = proc.Now() + step.deadline
+				value = fmt.Sprint(step.deadline)
+			}
+			for _, s := range step.servers {
+				s.Drain(proc, deadline, st)
+			}
+		case "recover":
+			for _, s := range step.servers {
+				s.Recover(proc.Now(), st, "scenario")
+			}
+		case "capacity":
+			for _, s := range step.servers {
+				i := s.ID - 1
+				s.SetCapacity(mbps[i]*step.factor, int(math.Floor(float64(maxConn[i])*step.factor)), cfg)
+			}
+			value = fmt.Sprint(step.factor)
+		case "owd":
+			for _, s := range step.servers {
+				s.SetOWDExtra(step.value)
+			}
+			value = fmt.Sprint(step.value)
+		case "traffic":
+			rc.SetFactor(step.factor)
+			value = fmt.Sprint(step.factor)
+		case "strategy":
+			if sw, ok := b.(*balancer.SwitchBalancer); ok {
+				if err := sw.Switch(step.strategy); err != nil {
+					panic("scenario: " + err.Error())
+				}
+			} else if step.strategy != cfg.Balancer.Strategy {
+				// например, обучаемая ql в режиме train
+				log.Printf("scenario: strategy %q at %.1fs ignored: balancer %T cannot switch strategies",
+					step.strategy, proc.Now(), b)
+			}
+			value = step.strategy
+		}
+
+		var ids []int
+		if step.action != "traffic" && step.action != "strategy" {
+			ids = make([]int, len(step.servers))
+			for i, s := range step.servers {
+				ids[i] = s.ID
+			}
+		}
+		st.AddScenario(&stats.ScenarioEvent{
+			T:       proc.Now(),
+			Action:  step.action,
+			Servers: ids,
+			Value:   value,
+			Undo:    step.undo,
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/balancer"
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+func TestScenarioFailTrafficAndUndo(t *testing.T) {
+	cfg := loadConfig(t, `
+simulation: {time_seconds: 30}
+cluster: {servers: 3}
+scenario:
+  - {at: 10, action: fail, servers: [2], duration: 5}
+  - {at: 3, action: traffic, factor: 2.5, duration: 4}
+  - {at: 20, action: recover, servers: [2]}
+  - {at: 20, action: fail}
+`)
+	rng := common.NewRNG(1)
+	servers := model.InitServers(cfg, rng)
+	sim := simgo.NewSimulation()
+	st := stats.NewStatistics(cfg)
+	rc := &rateCtrl{cfg: cfg, now: sim.Now, factor: 1}
+	b := balancer.BuildChain(cfg, servers, rng)
+	sim.Process(func(proc simgo.Process) { runScenario(proc, cfg, rc, b, servers, st) })
+
+	type probe struct {
+		t      float64
+		rate   float64
+		downed []bool
+	}
+	var probes []probe
+	sim.Process(func(proc simgo.Process) {
+		for _, at := range []float64{2, 5, 8, 12, 16, 25} {
+			proc.Wait(proc.Timeout(at - proc.Now()))
+			p := probe{t: at, rate: rc.Get()}
+			for _, s := range servers {
+				p.downed = append(p.downed, s.IsDown())
+			}
+			probes = append(probes, p)
+		}
+	})
+	sim.RunUntil(cfg.Simulation.TimeSeconds)
+	sim.Shutdown()
+
+	base := cfg.Traffic.BaseRPS
+	want := []probe{
+		{2, base, []bool{false, false, false}},
+		{5, 2.5 * base, []bool{false, false, false}},
+		{8, base, []bool{false, false, false}},
+		{12, base, []bool{false, true, false}},
+		{16, base, []bool{false, false, false}},
+		{25, base, []bool{true, true, true}},
+	}
+	for i, p := range probes {
+		if p.rate != want[i].rate {
+			t.Errorf("t=%v: rate = %v, want %v", p.t, p.rate, want[i].rate)
+		}
+		for j := range p.downed {
+			if p.downed[j] != want[i].downed[j] {
+				t.Errorf("t=%v: server %d down = %v, want %v", p.t, j+1, p.downed[j], want[i].downed[j])
+			}
+		}
+	}
+
+	// шаги упорядочены по времени, отмены помечены
+	var got []string
+	for _, ev := range st.Scenario {
+		got = append(got, ev.Action)
+		if ev.Undo != (ev.T == 7 || ev.T == 15) {
+			t.Errorf("%s at %v: undo = %v", ev.Action, ev.T, ev.Undo)
+		}
+	}
+	wantActions := []string{"traffic", "traffic", "fail", "recover", "recover", "fail"}
+	if len(got) != len(wantActions) {
+		t.Fatalf("scenario events = %v, want %v", got, wantActions)
+	}
+	for i := range got {
+		if got[i] != wantActions[i] {
+			t.Fatalf("scenario events = %v, want %v", got, wantActions)
+		}
+	}
+}
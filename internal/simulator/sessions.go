@@ -9,8 +9,16 @@ import (
 	"github.com/fschuetz04/simgo"
 )
 
-func chooseSession(cfg *config.Config, rng *common.RNG) int64 {
-	return rng.Int63n(cfg.Traffic.UsersAmount) + 1
+// lengthHint — оценка длины сессии, которую плеер сообщает балансировщику
+func lengthHint(cfg *config.Config, fragments int, rng *common.RNG) float64 {
+	switch cfg.Traffic.LengthHint {
+	case "exact":
+		return float64(fragments)
+	case "noisy":
+		sigma := cfg.Traffic.HintCV
+		return float64(fragments) * model.RandLogNormal(-sigma*sigma/2, sigma, rng) // мультипликативный шум со средним 1
+	}
+	return 0
 }
 
 func generateSessions(
@@ -23,79 +31,166 @@ func generateSessions(
 	st *stats.Statistics,
 	rng *common.RNG) {
 
+	arrivals := newArrivalProcess(cfg, rng)
+	users := newUserPool(cfg, rng)
+	cat := newCatalog(cfg, rng)
 	for {
-		rate := rc.Get()
-		ia := rng.ExpFloat64() / rate
-		if ia < 1e-6 { // TODO: to config?
-			ia = 1e-6
-		}
-		proc.Wait(proc.Timeout(ia))
+		work, n := arrivals.next(cfg.Traffic.BaseRPS)
+		waitWork(proc, cfg, rc, work)
 		now := proc.Now()
 
-		sessionID := chooseSession(cfg, rng)
-		st.AddArrival(&stats.ArrivalEvent{T: now, SessionID: sessionID})
+		for range n {
+			user, ok := users.choose()
+			sessionID := users.nextSessionID()
+			content, fragments := 0, 0
+			if cat != nil {
+				content, fragments = cat.choose()
+			} else {
+				fragments = max(1, int(cfg.Traffic.Fragments.Sample(rng)))
+			}
+			region := cfg.ChooseRegion(rng)
+			if !ok {
+				st.AddArrival(&stats.ArrivalEvent{
+					T: now, SessionID: sessionID, UserID: user, ContentID: content, Fragments: fragments, Region: region})
+				st.AddDrop(&stats.DropEvent{
+					ServerID: 0, SessionID: sessionID, T: now, Reason: "user_limit"})
+				continue
+			}
+			startSession(sim, cfg, balancer, st, rng, users, now, sessionID, user, content, fragments, region)
+		}
+	}
+}
 
-		pickedServer := balancer.PickServer(sessionID)
-		if pickedServer == nil {
-			st.AddDrop(&stats.DropEvent{
-				ServerID: 0, SessionID: sessionID, T: now, Reason: "no_server"})
-			continue
+// startSession регистрирует поступление сессии в момент now, выбирает ей сервер
+// и запускает процесс запроса фрагментов
+func startSession(
+	sim *simgo.Simulation,
+	cfg *config.Config,
+	balancer balancer.Balancer,
+	st *stats.Statistics,
+	rng *common.RNG,
+	users *userPool,
+	now float64,
+	sessionID int64,
+	userID int64,
+	contentID int,
+	fragments int,
+	region string) {
+
+	st.AddArrival(&stats.ArrivalEvent{
+		T: now, SessionID: sessionID, UserID: userID, ContentID: contentID, Fragments: fragments, Region: region})
+
+	session := &model.Session{
+		ID:        sessionID,
+		UserID:    userID,
+		ContentID: contentID,
+		Fragments: fragments,
+		Hint:      lengthHint(cfg, fragments, rng),
+		Region:    region,
+	}
+	if cfg.Balancer.HashKey == "content" {
+		session.HashKey = int64(contentID)
+	}
+
+	pickedServer := balancer.PickServer(session)
+	if pickedServer == nil {
+		st.AddDrop(&stats.DropEvent{
+			ServerID: 0, SessionID: sessionID, T: now, Reason: "no_server"})
+		return
+	}
+	st.AddPick(pickedServer.ID - 1)
+	users.acquire(userID)
+
+	sim.Process(func(proc simgo.Process) {
+		playSession(proc, cfg, balancer, st, rng, session, pickedServer, now)
+		st.EndSession(sessionID)
+		users.release(userID)
+	})
+}
+
+// playSession — запрос фрагментов сессии с ретраями и переключениями серверов
+func playSession(
+	proc simgo.Process,
+	cfg *config.Config,
+	balancer balancer.Balancer,
+	st *stats.Statistics,
+	rng *common.RNG,
+	session *model.Session,
+	pickedServer *model.Server,
+	now float64) {
+
+	sessionID, fragments := session.ID, session.Fragments
+	switches := 0
+	penalty := 0.0
+
+	for n := 0; n < fragments; n++ {
+		retries := 0
+		session.Segment = n
+
+		// с выводимого сервера сессия уходит между фрагментами (без учёта в max_switches);
+		// если перейти некуда — остаётся на нём
+		if pickedServer.MustMigrate(session, proc.Now()) {
+			if next := balancer.PickServer(session); next != nil {
+				st.AddRedirect(&stats.RedirectEvent{
+					SessionID: sessionID,
+					FromID:    pickedServer.ID,
+					ToID:      next.ID,
+					T:         proc.Now(),
+					Reason:    "drain",
+				})
+				session.Detach(cfg)
+				pickedServer = next
+				penalty += cfg.Cluster.RedirectPenalty.Sample(rng)
+			}
 		}
-		st.AddPick(pickedServer.ID - 1)
-
-		sim.Process(func(session simgo.Process) {
-			fragments := model.RandomFragments(rng)
-
-			switches := 0
-			penalty := 0.0
-
-			for n := 0; n < fragments; n++ {
-				retries := 0
-
-				for {
-					start := session.Now()
-					ok := pickedServer.HandleRequest(session, start, penalty, sessionID, cfg, st, rng)
-					if penalty > 0 {
-						penalty = 0.0
-					}
-					if ok {
-						break
-					}
-					retries++
-					if retries <= cfg.Cluster.MaxRetriesPerSegment {
-						continue
-					}
-
-					if switches >= cfg.Cluster.MaxSwitchesPerSession {
-						st.AddDrop(&stats.DropEvent{
-							ServerID:  pickedServer.ID,
-							SessionID: sessionID,
-							T:         start,
-							Reason:    "max_switches",
-						})
-						return
-					}
-
-					newPickedServer := balancer.PickServer(sessionID)
-					if newPickedServer == nil {
-						st.AddDrop(&stats.DropEvent{
-							ServerID: 0, SessionID: sessionID, T: now, Reason: "no_server"})
-						return
-					}
-					st.AddRedirect(&stats.RedirectEvent{
-						SessionID: sessionID,
-						FromID:    pickedServer.ID,
-						ToID:      newPickedServer.ID,
-						T:         start,
-					})
-					pickedServer = newPickedServer
-					switches++
-					penalty += 100 // TODO: distribution
-					retries = 0
-				}
-
-				session.Wait(session.Timeout(float64(cfg.Cluster.SegmentDuration)))
+
+		for {
+			start := proc.Now()
+			ok := pickedServer.HandleRequest(proc, start, penalty, session, cfg, st, rng)
+			if penalty > 0 {
+				penalty = 0.0
+			}
+			if ok {
+				break
+			}
+			retries++
+			if retries <= cfg.Cluster.MaxRetriesPerSegment && !pickedServer.IsDown() {
+				continue
+			}
+
+			if switches >= cfg.Cluster.MaxSwitchesPerSession {
+				st.AddDrop(&stats.DropEvent{
+					ServerID:  pickedServer.ID,
+					SessionID: sessionID,
+					T:         start,
+					Reason:    "max_switches",
+				})
+				session.Detach(cfg)
+				return
 			}
-		})
+
+			newPickedServer := balancer.PickServer(session)
+			if newPickedServer == nil {
+				st.AddDrop(&stats.DropEvent{
+					ServerID: 0, SessionID: sessionID, T: now, Reason: "no_server"})
+				session.Detach(cfg)
+				return
+			}
+			st.AddRedirect(&stats.RedirectEvent{
+				SessionID: sessionID,
+				FromID:    pickedServer.ID,
+				ToID:      newPickedServer.ID,
+				T:         start,
+				Reason:    "failed",
+			})
+			session.Detach(cfg)
+			pickedServer = newPickedServer
+			switches++
+			penalty += cfg.Cluster.RedirectPenalty.Sample(rng)
+			retries = 0
+		}
+
+		proc.Wait(proc.Timeout(float64(cfg.Cluster.SegmentDuration)))
 	}
+	session.Detach(cfg)
 }
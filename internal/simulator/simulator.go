@@ -11,42 +11,91 @@ import (
 	"github.com/fschuetz04/simgo"
 )
 
+// rateCtrl — текущая интенсивность: профиль traffic.profile/spikes в момент now()
+// с множителем сценария
 type rateCtrl struct {
-	mu      sync.RWMutex
-	base    float64
-	current float64
+	mu     sync.RWMutex
+	cfg    *config.Config
+	now    func() float64
+	factor float64 // множитель сценария (scenario: traffic)
 }
 
 func (r *rateCtrl) Get() float64 {
 	r.mu.RLock()
-	v := r.current
+	f := r.factor
 	r.mu.RUnlock()
-	return v
+	return profileRate(r.cfg, r.now()) * f
 }
 
-func (r *rateCtrl) Set(v float64) {
+func (r *rateCtrl) Base() float64 {
+	return r.cfg.Traffic.BaseRPS
+}
+
+func (r *rateCtrl) SetFactor(f float64) {
 	r.mu.Lock()
-	r.current = v
+	r.factor = f
 	r.mu.Unlock()
 }
 
-func Run(cfg *config.Config, servers []*model.Server, balancer balancer.Balancer, rng *common.RNG) *stats.Statistics {
+func Run(cfg *config.Config, servers []*model.Server, b balancer.Balancer, rng *common.RNG) *stats.Statistics {
 	simulation := simgo.NewSimulation()
 	statistics := stats.NewStatistics(cfg)
 
-	rc := &rateCtrl{base: cfg.Traffic.BaseRPS, current: cfg.Traffic.BaseRPS}
+	rc := &rateCtrl{cfg: cfg, now: simulation.Now, factor: 1}
+	balancer.Attach(b, &balancer.Env{Sim: simulation, Stats: statistics, Rate: rc})
 
 	simulation.Process(func(proc simgo.Process) { collectSnapshots(proc, cfg, servers) })
-	simulation.Process(func(proc simgo.Process) { generateSpikes(proc, cfg, rc) })
-	simulation.Process(func(proc simgo.Process) {
-		generateSessions(proc, simulation, cfg, rc, balancer, servers, statistics, rng)
-	})
+	simulation.Process(func(proc simgo.Process) { recordRate(proc, cfg, rc, statistics) })
+	if cfg.Autoscale.MaxServers > 0 {
+		simulation.Process(func(proc simgo.Process) { autoscale(proc, cfg, servers, statistics) })
+	}
+	if cfg.RollingRestart.Batch > 0 {
+		simulation.Process(func(proc simgo.Process) { rollingRestart(proc, cfg, servers, statistics) })
+	}
+	if len(cfg.Scenario) > 0 {
+		simulation.Process(func(proc simgo.Process) { runScenario(proc, cfg, rc, b, servers, statistics) })
+	}
+	if len(cfg.Traffic.Trace.Arrivals) > 0 {
+		simulation.Process(func(proc simgo.Process) {
+			replayTrace(proc, simulation, cfg, b, statistics, rng)
+		})
+	} else {
+		simulation.Process(func(proc simgo.Process) {
+			generateSessions(proc, simulation, cfg, rc, b, servers, statistics, rng)
+		})
+	}
 
 	for _, srv := range servers {
 		s := srv
 		simulation.Process(func(proc simgo.Process) { jitterTick(proc, cfg, s, rng) })
+		if cfg.Failures.MTBF > 0 {
+			simulation.Process(func(proc simgo.Process) { failureTick(proc, cfg, s, statistics, rng) })
+		}
+		if cfg.Degradation.MTBD > 0 {
+			group := []*model.Server{s}
+			simulation.Process(func(proc simgo.Process) { degradationTick(proc, cfg, group, "", statistics, rng) })
+		}
+	}
+	for i := range cfg.Domains {
+		d := &cfg.Domains[i]
+		group := model.InDomain(servers, d.Name)
+		if d.MTBF > 0 {
+			simulation.Process(func(proc simgo.Process) {
+				domainFailureTick(proc, cfg, d.Name, group, d.MTBF, d.MTTR, statistics, rng)
+			})
+		}
+		if d.SpikeP > 0 {
+			simulation.Process(func(proc simgo.Process) {
+				domainSpikeTick(proc, cfg, d.Name, group, d.SpikeP, d.SpikeExtra, d.SpikeDur, statistics, rng)
+			})
+		}
+	}
+	for _, label := range cfg.Degradation.Labels {
+		group := labelled(servers, label)
+		simulation.Process(func(proc simgo.Process) { degradationTick(proc, cfg, group, label, statistics, rng) })
 	}
 
 	simulation.RunUntil(cfg.Simulation.TimeSeconds)
+	simulation.Shutdown()
 	return statistics
 }
@@ -0,0 +1,38 @@
+package simulator
+
+import (
+	"github.com/emrzvv/lb-research/internal/balancer"
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+// replayTrace воспроизводит поступления из traffic.trace вместо generateSessions;
+// регионы без значения в трассе выбраны при загрузке конфига; traffic.users не действует —
+// пользователи берутся из трассы
+func replayTrace(
+	proc simgo.Process,
+	sim *simgo.Simulation,
+	cfg *config.Config,
+	balancer balancer.Balancer,
+	st *stats.Statistics,
+	rng *common.RNG) {
+
+	users := newUserPool(cfg, rng)
+	users.returns = false // пользователи берутся из трассы, возвраты не разыгрываются
+	users.lastID = cfg.Traffic.Trace.MaxSessionID
+	for _, a := range cfg.Traffic.Trace.Arrivals {
+		if a.T >= cfg.Simulation.TimeSeconds {
+			return
+		}
+		if wait := a.T - proc.Now(); wait > 0 {
+			proc.Wait(proc.Timeout(wait))
+		}
+		sessionID := a.SessionID
+		if sessionID == 0 {
+			sessionID = users.nextSessionID()
+		}
+		startSession(sim, cfg, balancer, st, rng, users, proc.Now(), sessionID, a.UserID, a.ContentID, a.Fragments, a.Region)
+	}
+}
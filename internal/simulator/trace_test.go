@@ -0,0 +1,51 @@
+package simulator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/balancer"
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+func TestReplayAssignsSessionIDsAfterTraceIDs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"trace.csv": "time_s,session_id,user_id,fragments\n0,,1,2\n1,2,1,2\n2,,2,2\n3,1,3,2\n",
+		"cfg.yaml":  "simulation: {time_seconds: 20}\ncluster: {servers: 2}\ntraffic: {trace: {file: trace.csv}}\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := config.Load(filepath.Join(dir, "cfg.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := common.NewRNG(1)
+	servers := model.InitServers(cfg, rng)
+	b := balancer.BuildChain(cfg, servers, rng)
+	sim := simgo.NewSimulation()
+	st := stats.NewStatistics(cfg)
+	balancer.Attach(b, &balancer.Env{Sim: sim, Stats: st})
+	sim.Process(func(proc simgo.Process) { replayTrace(proc, sim, cfg, b, st, rng) })
+	sim.RunUntil(cfg.Simulation.TimeSeconds)
+	sim.Shutdown()
+
+	// номера без session_id выдаются после наибольшего номера трассы
+	want := []int64{3, 2, 4, 1}
+	if len(st.Arrivals) != len(want) {
+		t.Fatalf("arrivals = %d, want %d", len(st.Arrivals), len(want))
+	}
+	for i, a := range st.Arrivals {
+		if a.SessionID != want[i] {
+			t.Fatalf("arrival %d: session id = %d, want %d", i, a.SessionID, want[i])
+		}
+	}
+}
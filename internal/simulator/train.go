@@ -0,0 +1,25 @@
+package simulator
+
+import (
+	"log"
+
+	"github.com/emrzvv/lb-research/internal/balancer"
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+)
+
+// TrainQLearning обучает Q-таблицу на cfg.Balancer.QLEpisodes эпизодах.
+// Эпизод i использует seed+i, поэтому результат детерминирован при фиксированном seed.
+func TrainQLearning(cfg *config.Config) *balancer.QTable {
+	table := balancer.NewQTable(cfg.Balancer.QLUtilBuckets)
+	for ep := 0; ep < cfg.Balancer.QLEpisodes; ep++ {
+		rng := common.NewRNG(cfg.Simulation.Seed + int64(ep))
+		servers := model.InitServers(cfg, rng)
+		b := balancer.NewQLearningBalancer(servers, cfg, rng, table, true)
+		st := Run(cfg, servers, b, rng)
+		log.Printf("ql episode %d/%d: requests %d, drops %d",
+			ep+1, cfg.Balancer.QLEpisodes, len(st.ServerRequests), len(st.Drops))
+	}
+	return table
+}
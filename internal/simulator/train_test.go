@@ -0,0 +1,42 @@
+package simulator
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/config"
+)
+
+// loadConfig — конфигурация из YAML с умолчаниями config.Load
+func loadConfig(t *testing.T, yaml string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cfg.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestTrainQLearningIsDeterministic(t *testing.T) {
+	cfg := loadConfig(t, `
+simulation: {time_seconds: 30, seed: 3}
+traffic: {base_rps: 30}
+cluster: {servers: 6, cap_mean_mbps: 40}
+spikes: [{at: 10, duration: 5, factor: 3}]
+balancer: {ql_episodes: 2}
+`)
+	a := TrainQLearning(cfg)
+	b := TrainQLearning(cfg)
+	if !slices.Equal(a.Q, b.Q) {
+		t.Fatalf("q-tables differ for the same seed")
+	}
+	if !slices.ContainsFunc(a.Q, func(q float64) bool { return q != 0 }) {
+		t.Fatalf("q-table was not updated")
+	}
+}
@@ -0,0 +1,107 @@
+package simulator
+
+import (
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+)
+
+// maxUserRedraws — сколько раз перевыбирается пользователь, упёршийся в max_concurrent
+const maxUserRedraws = 32
+
+// maxReturning — сколько разных пользователей с завершёнными сессиями ждут возврата;
+// при переполнении вытесняется случайный
+const maxReturning = 4096
+
+// userPool — модель пользователей (traffic.users): активность по Zipf, возвраты
+// пользователей с завершёнными сессиями и предел одновременных сессий
+type userPool struct {
+	cfg *config.Config
+	rng *common.RNG
+
+	cdf       []float64 // накопленные веса Zipf по рангу пользователя (nil — равномерно)
+	active    map[int64]int
+	returns   bool          // запоминать пользователей для возврата (return_p > 0, не при воспроизведении трассы)
+	returning []int64       // разные пользователи, чьи сессии завершились (не больше maxReturning)
+	waiting   map[int64]int // индекс пользователя в returning
+	lastID    int64         // последний выданный session_id
+}
+
+func newUserPool(cfg *config.Config, rng *common.RNG) *userPool {
+	u := &userPool{
+		cfg:     cfg,
+		rng:     rng,
+		active:  make(map[int64]int),
+		returns: cfg.Traffic.Users.ReturnP > 0,
+		waiting: make(map[int64]int),
+	}
+	if s := cfg.Traffic.Users.Zipf; s > 0 {
+		u.cdf = zipfCDF(int(cfg.Traffic.UsersAmount), s)
+	}
+	return u
+}
+
+// nextSessionID — уникальный номер сессии
+func (u *userPool) nextSessionID() int64 {
+	u.lastID++
+	return u.lastID
+}
+
+// draw — пользователь из популяции: ранг по Zipf, без Zipf — равномерно
+func (u *userPool) draw() int64 {
+	if u.cdf == nil {
+		return u.rng.Int63n(u.cfg.Traffic.UsersAmount) + 1
+	}
+	return int64(sampleCDF(u.cdf, u.rng))
+}
+
+// choose — пользователь новой сессии; false — все выбранные пользователи упёрлись
+// в max_concurrent (возвращается последний из них)
+func (u *userPool) choose() (int64, bool) {
+	limit := u.cfg.Traffic.Users.MaxConcurrent
+	var user int64
+	for range maxUserRedraws {
+		if p := u.cfg.Traffic.Users.ReturnP; p > 0 && len(u.returning) > 0 && u.rng.Float64() < p {
+			user = u.returning[u.rng.Intn(len(u.returning))]
+			u.forget(user)
+		} else {
+			user = u.draw()
+		}
+		if limit == 0 || u.active[user] < limit {
+			return user, true
+		}
+	}
+	return user, false
+}
+
+// acquire учитывает начало сессии пользователя
+func (u *userPool) acquire(user int64) {
+	u.active[user]++
+}
+
+// release учитывает окончание сессии пользователя
+func (u *userPool) release(user int64) {
+	if u.active[user]--; u.active[user] <= 0 {
+		delete(u.active, user)
+	}
+	if !u.returns {
+		return
+	}
+	if _, ok := u.waiting[user]; ok {
+		return
+	}
+	if len(u.returning) >= maxReturning {
+		u.forget(u.returning[u.rng.Intn(len(u.returning))])
+	}
+	u.waiting[user] = len(u.returning)
+	u.returning = append(u.returning, user)
+}
+
+// forget убирает пользователя из ожидающих возврата
+func (u *userPool) forget(user int64) {
+	i := u.waiting[user]
+	last := u.returning[len(u.returning)-1]
+	u.returning[i] = last
+	u.waiting[last] = i
+	u.returning = u.returning[:len(u.returning)-1]
+	delete(u.waiting, user)
+}
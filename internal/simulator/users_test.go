@@ -0,0 +1,138 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/emrzvv/lb-research/internal/balancer"
+	"github.com/emrzvv/lb-research/internal/common"
+	"github.com/emrzvv/lb-research/internal/config"
+	"github.com/emrzvv/lb-research/internal/model"
+	"github.com/emrzvv/lb-research/internal/stats"
+	"github.com/fschuetz04/simgo"
+)
+
+func TestUserPoolZipfAndReturns(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Traffic.UsersAmount = 1000
+	cfg.Traffic.Users.Zipf = 1
+	u := newUserPool(cfg, common.NewRNG(1))
+
+	// доля ранга k — (1/k) / H(1000)
+	const n = 100_000
+	counts := make(map[int64]int)
+	for range n {
+		user, ok := u.choose()
+		if !ok || user < 1 || user > 1000 {
+			t.Fatalf("choose = %d, %v", user, ok)
+		}
+		counts[user]++
+	}
+	h := 0.0
+	for k := 1; k <= 1000; k++ {
+		h += 1 / float64(k)
+	}
+	for _, k := range []int64{1, 2, 10} {
+		want := 1 / float64(k) / h
+		if got := float64(counts[k]) / n; math.Abs(got-want) > 0.1*want {
+			t.Errorf("rank %d share = %.4f, want %.4f", k, got, want)
+		}
+	}
+
+	// return_p 1: новую сессию открывает пользователь с завершённой сессией, каждый — один раз
+	cfg = &config.Config{}
+	cfg.Traffic.UsersAmount = 1 << 40
+	cfg.Traffic.Users.ReturnP = 1
+	u = newUserPool(cfg, common.NewRNG(1))
+	u.acquire(7)
+	u.release(7)
+	u.acquire(7)
+	u.release(7)
+	if user, _ := u.choose(); user != 7 {
+		t.Fatalf("returning user = %d, want 7", user)
+	}
+	if user, _ := u.choose(); user == 7 {
+		t.Fatalf("user 7 returned twice after one wait")
+	}
+
+	// ожидающие возврата — разные пользователи, не больше maxReturning
+	for user := int64(1); user <= 2*maxReturning; user++ {
+		u.acquire(user)
+		u.release(user)
+		u.acquire(1)
+		u.release(1)
+	}
+	if len(u.returning) != maxReturning || len(u.waiting) != maxReturning {
+		t.Fatalf("returning = %d, waiting = %d, want %d", len(u.returning), len(u.waiting), maxReturning)
+	}
+	for i, user := range u.returning {
+		if u.waiting[user] != i {
+			t.Fatalf("waiting[%d] = %d, want %d", user, u.waiting[user], i)
+		}
+	}
+
+	// при воспроизведении трассы возвраты не запоминаются
+	u = newUserPool(cfg, common.NewRNG(1))
+	u.returns = false
+	u.acquire(7)
+	u.release(7)
+	if len(u.returning) != 0 {
+		t.Fatalf("replay pool keeps %d returning users", len(u.returning))
+	}
+}
+
+func TestUserLimitDropsSessions(t *testing.T) {
+	cfg := loadConfig(t, `
+simulation: {time_seconds: 60}
+traffic:
+  base_rps: 2
+  users_amount: 3
+  users: {max_concurrent: 1}
+  fragments: {type: constant, value: 20}
+cluster: {servers: 4}
+balancer: {strategy: p2c}
+`)
+	rng := common.NewRNG(1)
+	servers := model.InitServers(cfg, rng)
+	b := balancer.BuildChain(cfg, servers, rng)
+	sim := simgo.NewSimulation()
+	st := stats.NewStatistics(cfg)
+	rc := &rateCtrl{cfg: cfg, now: sim.Now, factor: 1}
+	balancer.Attach(b, &balancer.Env{Sim: sim, Stats: st, Rate: rc})
+
+	ends := make(map[int64]float64)
+	st.OnSessionEnd(func(id int64) { ends[id] = sim.Now() })
+	sim.Process(func(proc simgo.Process) { generateSessions(proc, sim, cfg, rc, b, servers, st, rng) })
+	sim.RunUntil(cfg.Simulation.TimeSeconds)
+	sim.Shutdown()
+
+	limited := make(map[int64]bool)
+	for _, d := range st.Drops {
+		if d.Reason == "user_limit" {
+			limited[d.SessionID] = true
+		}
+	}
+	if len(limited) == 0 {
+		t.Fatalf("no user_limit drops with 3 users, max_concurrent 1 and ~40 s sessions")
+	}
+	// сессия пользователя открыта от поступления до EndSession (или до конца прогона)
+	type span struct{ start, end float64 }
+	spans := make(map[int64][]span)
+	for _, a := range st.Arrivals {
+		if limited[a.SessionID] {
+			continue
+		}
+		end, ok := ends[a.SessionID]
+		if !ok {
+			end = cfg.Simulation.TimeSeconds
+		}
+		spans[a.UserID] = append(spans[a.UserID], span{a.T, end})
+	}
+	for user, ss := range spans {
+		for i := 1; i < len(ss); i++ {
+			if ss[i].start < ss[i-1].end {
+				t.Fatalf("user %d: session at %v started before the previous one ended at %v", user, ss[i].start, ss[i-1].end)
+			}
+		}
+	}
+}
@@ -13,19 +13,40 @@ type Statistics struct {
 	Drops          []*DropEvent
 	Redirects      []*RedirectEvent
 	Picks          []int
+	Posteriors     []*PosteriorEvent
+	Forecasts      []*ForecastEvent
+	Spillovers     []*SpilloverEvent
+	States         []*StateEvent
+	Scenario       []*ScenarioEvent
+	Degradations   []*DegradationEvent
+	DomainEvents   []*DomainEvent
+	Scaling        []*ScaleEvent
+	Rates          []*RateEvent
+
+	arrivalHooks []func(*ArrivalEvent)
+	requestHooks []func(*RequestEvent)
+	dropHooks    []func(*DropEvent)
+	endHooks     []func(sessionID int64)
 }
 
 type ArrivalEvent struct {
 	T         float64
 	SessionID int64
+	UserID    int64
+	ContentID int
+	Fragments int
+	Region    string
 }
 
 type RequestEvent struct {
 	ServerID   int
 	SessiontID int64
+	ContentID  int
 	T1         float64
 	T2         float64
-	Duration   float64
+	Duration   float64 // от поступления запроса до ответа, со штрафом редиректа
+	Queued     float64 // ожидание в очереди сервера
+	Service    float64 // обслуживание сервером: без очереди и штрафа редиректа
 }
 
 type DropEvent struct {
@@ -40,6 +61,81 @@ type RedirectEvent struct {
 	FromID    int
 	ToID      int
 	T         float64
+	Reason    string // failed — запрос не обслужен; drain — сервер выводится из балансировки
+}
+
+// PosteriorEvent — параметры Beta-апостериорного распределения сервера в момент T
+type PosteriorEvent struct {
+	T        float64
+	ServerID int
+	Alpha    float64
+	Beta     float64
+}
+
+// ForecastEvent — фактическая и прогнозная интенсивность поступлений на конец бина T
+type ForecastEvent struct {
+	T         float64
+	Actual    float64
+	Forecast  float64
+	Spreading bool
+}
+
+// SpilloverEvent — сессия ушла из предпочтительного PoP в другой
+type SpilloverEvent struct {
+	SessionID int64
+	FromPoP   string
+	ToPoP     string
+	T         float64
+}
+
+// StateEvent — смена состояния сервера (отказ, восстановление)
+type StateEvent struct {
+	T        float64
+	ServerID int
+	State    string
+}
+
+// DegradationEvent — деградация сервера на [T1, T2] (Label — при коррелированной по метке)
+type DegradationEvent struct {
+	T1        float64
+	T2        float64
+	ServerID  int
+	Label     string
+	Bandwidth float64
+	Latency   float64
+}
+
+// DomainEvent — отказ (Kind = "fail") или всплеск OWD ("spike") домена на [T1, T2]
+type DomainEvent struct {
+	T1     float64
+	T2     float64
+	Domain string
+	Kind   string
+}
+
+// RateEvent — интенсивность поступления сессий в момент T
+type RateEvent struct {
+	T   float64
+	RPS float64
+}
+
+// ScaleEvent — решение автомасштабирования: число работающих и запускаемых
+// серверов, утилизация и действие (out — добавление, in — вывод, пусто — без изменений)
+type ScaleEvent struct {
+	T            float64
+	Active       int
+	Provisioning int
+	Utilisation  float64
+	Action       string
+}
+
+// ScenarioEvent — выполненное действие сценария (Undo — отмена по duration)
+type ScenarioEvent struct {
+	T       float64
+	Action  string
+	Servers []int
+	Value   string
+	Undo    bool
 }
 
 func NewStatistics(cfg *config.Config) *Statistics {
@@ -49,14 +145,64 @@ func NewStatistics(cfg *config.Config) *Statistics {
 		ServerRequests: make([]*RequestEvent, 0),
 		Drops:          make([]*DropEvent, 0),
 		Redirects:      make([]*RedirectEvent, 0),
-		Picks:          make([]int, cfg.Cluster.Servers),
+		Picks:          make([]int, cfg.TotalServers()),
+		Posteriors:     make([]*PosteriorEvent, 0),
+		Forecasts:      make([]*ForecastEvent, 0),
+		Spillovers:     make([]*SpilloverEvent, 0),
+		States:         make([]*StateEvent, 0),
+		Scenario:       make([]*ScenarioEvent, 0),
+		Degradations:   make([]*DegradationEvent, 0),
+		DomainEvents:   make([]*DomainEvent, 0),
+		Scaling:        make([]*ScaleEvent, 0),
+		Rates:          make([]*RateEvent, 0),
+	}
+}
+
+// OnArrival, OnRequest и OnDrop регистрируют обработчики, которые вызываются
+// синхронно после записи соответствующего события (обратная связь для балансировщиков).
+func (st *Statistics) OnArrival(fn func(*ArrivalEvent)) {
+	st.mu.Lock()
+	st.arrivalHooks = append(st.arrivalHooks, fn)
+	st.mu.Unlock()
+}
+
+func (st *Statistics) OnRequest(fn func(*RequestEvent)) {
+	st.mu.Lock()
+	st.requestHooks = append(st.requestHooks, fn)
+	st.mu.Unlock()
+}
+
+func (st *Statistics) OnDrop(fn func(*DropEvent)) {
+	st.mu.Lock()
+	st.dropHooks = append(st.dropHooks, fn)
+	st.mu.Unlock()
+}
+
+// OnSessionEnd регистрирует обработчик окончания сессии (досмотрена или отброшена)
+func (st *Statistics) OnSessionEnd(fn func(sessionID int64)) {
+	st.mu.Lock()
+	st.endHooks = append(st.endHooks, fn)
+	st.mu.Unlock()
+}
+
+// EndSession сообщает обработчикам об окончании сессии; в статистику не записывается
+func (st *Statistics) EndSession(sessionID int64) {
+	st.mu.Lock()
+	hooks := st.endHooks
+	st.mu.Unlock()
+	for _, fn := range hooks {
+		fn(sessionID)
 	}
 }
 
 func (st *Statistics) AddArrival(ae *ArrivalEvent) {
 	st.mu.Lock()
 	st.Arrivals = append(st.Arrivals, ae)
+	hooks := st.arrivalHooks
 	st.mu.Unlock()
+	for _, fn := range hooks {
+		fn(ae)
+	}
 }
 
 func (st *Statistics) AddPick(id int) {
@@ -68,13 +214,21 @@ func (st *Statistics) AddPick(id int) {
 func (st *Statistics) AddDrop(de *DropEvent) {
 	st.mu.Lock()
 	st.Drops = append(st.Drops, de)
+	hooks := st.dropHooks
 	st.mu.Unlock()
+	for _, fn := range hooks {
+		fn(de)
+	}
 }
 
 func (st *Statistics) AddRequest(re *RequestEvent) {
 	st.mu.Lock()
 	st.ServerRequests = append(st.ServerRequests, re)
+	hooks := st.requestHooks
 	st.mu.Unlock()
+	for _, fn := range hooks {
+		fn(re)
+	}
 }
 
 func (st *Statistics) AddRedirect(re *RedirectEvent) {
@@ -82,3 +236,57 @@ func (st *Statistics) AddRedirect(re *RedirectEvent) {
 	st.Redirects = append(st.Redirects, re)
 	st.mu.Unlock()
 }
+
+func (st *Statistics) AddPosterior(pe *PosteriorEvent) {
+	st.mu.Lock()
+	st.Posteriors = append(st.Posteriors, pe)
+	st.mu.Unlock()
+}
+
+func (st *Statistics) AddForecast(fe *ForecastEvent) {
+	st.mu.Lock()
+	st.Forecasts = append(st.Forecasts, fe)
+	st.mu.Unlock()
+}
+
+func (st *Statistics) AddSpillover(se *SpilloverEvent) {
+	st.mu.Lock()
+	st.Spillovers = append(st.Spillovers, se)
+	st.mu.Unlock()
+}
+
+func (st *Statistics) AddState(se *StateEvent) {
+	st.mu.Lock()
+	st.States = append(st.States, se)
+	st.mu.Unlock()
+}
+
+func (st *Statistics) AddScenario(se *ScenarioEvent) {
+	st.mu.Lock()
+	st.Scenario = append(st.Scenario, se)
+	st.mu.Unlock()
+}
+
+func (st *Statistics) AddDegradation(de *DegradationEvent) {
+	st.mu.Lock()
+	st.Degradations = append(st.Degradations, de)
+	st.mu.Unlock()
+}
+
+func (st *Statistics) AddDomainEvent(de *DomainEvent) {
+	st.mu.Lock()
+	st.DomainEvents = append(st.DomainEvents, de)
+	st.mu.Unlock()
+}
+
+func (st *Statistics) AddScale(se *ScaleEvent) {
+	st.mu.Lock()
+	st.Scaling = append(st.Scaling, se)
+	st.mu.Unlock()
+}
+
+func (st *Statistics) AddRate(re *RateEvent) {
+	st.mu.Lock()
+	st.Rates = append(st.Rates, re)
+	st.mu.Unlock()
+}
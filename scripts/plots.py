@@ -31,12 +31,13 @@ def r(name: str) -> pd.DataFrame:
 warnings.filterwarnings('ignore', category=FutureWarning)
 sns.set_theme(style="whitegrid")
 
-snaps    = r("snapshots.csv")    # time_s,server_id,connections,owd_ms
-arrivals = r("arrivals.csv")     # time_s,session_id
-req      = r("requests.csv")     # server_id,session_id,start_s,end_s,duration
+snaps    = r("snapshots.csv")    # time_s,server_id,connections,sessions,queue,owd_ms
+arrivals = r("arrivals.csv")     # time_s,session_id,user_id,content_id,fragments,region
+req      = r("requests.csv")     # server_id,session_id,content_id,start_s,end_s,duration,service
 drops    = r("drops.csv")        # server_id,session_id,time_s,reason
-cfg      = r("servers.csv")      # id,mbps,owd_ms,max_conn
+cfg      = r("servers.csv")      # id,mbps,owd_ms,max_conn,pop,region,pool,labels,domains
 summ     = r("summary.csv")      # id,picked,served,dropped
+rate     = r("rate.csv")         # time_s,rate_rps
 
 n_srv     = req.server_id.nunique()
 palette   = sns.color_palette("tab20", n_colors=n_srv)
@@ -54,6 +55,8 @@ step = 1.0
 arrivals["bin"] = (arrivals.time_s // step) * step
 ts = arrivals.groupby("bin").size().reset_index(name="count")
 sns.lineplot(data=ts, x="bin", y="count", ax=ax_a, marker="o", linewidth=1)
+ax_a.plot(rate.time_s, rate.rate_rps * step, color="red", linewidth=1.5, label="rate profile")
+ax_a.legend()
 ax_a.set(title=f"Arrivals / {step:.0f}s", xlabel="time (s)", ylabel="arrivals")
 
 sess = snaps.groupby("time_s").connections.sum().reset_index()